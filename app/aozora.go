@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultPagePause は青空文庫形式の改ページの位置に挿入する無音の既定の長さです。
+const DefaultPagePause = 2 * time.Second
+
+// AozoraOptions は青空文庫形式のテキストを読み上げ用のテキストに変換する設定です。
+type AozoraOptions struct {
+	// PagePause は改ページ・改丁などの位置に挿入する無音の長さです。0 の場合は段落の区切りにします。
+	PagePause time.Duration
+	// HeadingPause は見出しの後に挿入する無音の長さです。0 の場合は無音を挿入しません。
+	HeadingPause time.Duration
+}
+
+// DefaultAozoraOptions は既定の変換の設定を返します。
+func DefaultAozoraOptions() AozoraOptions {
+	return AozoraOptions{PagePause: DefaultPagePause, HeadingPause: DefaultHeadingPause}
+}
+
+var (
+	aoSeparator = regexp.MustCompile(`^-{10,}$`)
+	aoFooter    = regexp.MustCompile(`^(?:底本：|［＃本文終わり］)`)
+	// aoGaiji は外字の注記です。Unicode の符号位置が書かれていればその文字にします。
+	aoGaiji      = regexp.MustCompile(`※［＃[^］]*?U\+([0-9A-Fa-f]{4,6})[^］]*］|※［＃[^］]*］`)
+	aoPage       = regexp.MustCompile(`^［＃改(?:ページ|丁|見開き|段)］$`)
+	aoHeading    = regexp.MustCompile(`［＃[^］]*見出し[^］]*］`)
+	aoHeadBlock  = regexp.MustCompile(`^［＃ここから[^］]*見出し］$`)
+	aoHeadEnd    = regexp.MustCompile(`^［＃ここで[^］]*見出し終わり］$`)
+	aoAnnotation = regexp.MustCompile(`［＃[^］]*］`)
+	// aoAccent はアクセント分解の記法(〔cafe'〕 など)です。括弧だけを取り除きます。
+	aoAccent = regexp.MustCompile(`〔([^〕]*[a-zA-Z][^〕]*)〕`)
+)
+
+// AozoraText は青空文庫形式のテキストから本文を取り出し、SegmentText で台本にできるテキストに変換します。
+//
+//   - 冒頭の表題・著者名と記号の説明、末尾の底本などの書誌情報を取り除きます。
+//     記号の説明の区切り線がない場合は、冒頭の空行までの短い数行だけを表題と著者名とみなします。
+//   - ルビ(｜吉岡《よしおか》)はそのまま残し、ParseRuby で読み方として扱います。
+//   - 改ページ・改丁などの注記は opts.PagePause の無音にします。
+//   - 見出しの注記がある行は1行の文にして、後ろに opts.HeadingPause の無音を挿入します。
+//   - その他の［＃...］の注記は取り除き、外字は Unicode の符号位置が分かる場合だけその文字にします。
+func AozoraText(src string, opts AozoraOptions) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	lines = aozoraBody(lines)
+
+	pause := func(d time.Duration) string {
+		return fmt.Sprintf("[pause=%s]", d)
+	}
+	var out []string
+	inHeading := false
+	for _, line := range lines {
+		line = aoGaiji.ReplaceAllStringFunc(line, func(m string) string {
+			sub := aoGaiji.FindStringSubmatch(m)
+			if sub[1] == "" {
+				return ""
+			}
+			r, _ := strconv.ParseInt(sub[1], 16, 32)
+			return string(rune(r))
+		})
+		line = aoAccent.ReplaceAllString(line, "$1")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case aoPage.MatchString(trimmed):
+			out = append(out, "")
+			if opts.PagePause > 0 {
+				out = append(out, pause(opts.PagePause))
+			}
+			continue
+		case aoHeadBlock.MatchString(trimmed):
+			inHeading = true
+			continue
+		case aoHeadEnd.MatchString(trimmed):
+			inHeading = false
+			continue
+		}
+		heading := inHeading || aoHeading.MatchString(line)
+		text := strings.TrimSpace(aoAnnotation.ReplaceAllString(line, ""))
+		if heading && strings.Trim(text, "　") != "" {
+			out = append(out, sentence(text))
+			if opts.HeadingPause > 0 {
+				out = append(out, pause(opts.HeadingPause))
+			}
+			continue
+		}
+		if text == "" && trimmed != "" {
+			// 注記だけの行は空行(段落の区切り)にしない
+			continue
+		}
+		out = append(out, text)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// aozoraBody は冒頭と末尾の書誌情報を除いた本文の行を返します。
+func aozoraBody(lines []string) []string {
+	// 末尾の底本などの書誌情報
+	for i, line := range lines {
+		if aoFooter.MatchString(strings.TrimSpace(line)) {
+			lines = lines[:i]
+			break
+		}
+	}
+	// 冒頭の表題・著者名と記号の説明は区切り線までにある
+	seps := 0
+	for i, line := range lines {
+		if aoSeparator.MatchString(strings.TrimSpace(line)) {
+			if seps++; seps == 2 {
+				return lines[i+1:]
+			}
+		}
+	}
+	// 区切り線がなければ、冒頭の空行までの句点などを含まない短い数行を表題と著者名とみなす
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if i > 0 && i < len(lines)-1 {
+				return lines[i+1:]
+			}
+			break
+		}
+		if i >= maxAozoraHeaderLines || utf8.RuneCountInString(line) > maxAozoraHeaderLength || strings.ContainsAny(line, "。！？」") {
+			break
+		}
+	}
+	return lines
+}
+
+// 区切り線のない青空文庫形式のテキストで、表題と著者名とみなす冒頭の行数と1行の文字数の上限
+const (
+	maxAozoraHeaderLines  = 3
+	maxAozoraHeaderLength = 40
+)
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+const aozoraSample = `羅生門
+芥川龍之介
+
+-------------------------------------------------------
+【テキスト中に現れる記号について】
+
+《》：ルビ
+（例）下人《げにん》
+
+［＃］：入力者注　主に外字の説明や、傍点の位置の指定
+-------------------------------------------------------
+
+［＃３字下げ］一［＃「一」は中見出し］
+　ある日の暮方の事である。一人の｜下人《げにん》が、羅生門の下で雨やみを待っていた。
+［＃ここから２字下げ］
+　※［＃「木＋吋のつくり」、U+6751、12-3］は広い。※［＃「てへん＋劣」、第3水準1-84-77］
+［＃ここで字下げ終わり］
+　下人は［＃「下人は」に傍点］〔cafe'〕にいた。
+［＃改ページ］
+　下人の行方は、誰も知らない。
+
+
+
+底本：「芥川龍之介全集1」ちくま文庫、筑摩書房
+　　1986（昭和61）年9月24日第1刷発行
+入力：j.utsumi
+`
+
+func TestAozoraText(t *testing.T) {
+	got := AozoraText(strings.ReplaceAll(aozoraSample, "\n", "\r\n"), AozoraOptions{PagePause: 2 * time.Second, HeadingPause: time.Second})
+	want := strings.Join([]string{
+		"一。",
+		"[pause=1s]",
+		"ある日の暮方の事である。一人の｜下人《げにん》が、羅生門の下で雨やみを待っていた。",
+		"村は広い。",
+		"下人はcafe'にいた。",
+		"",
+		"[pause=2s]",
+		"下人の行方は、誰も知らない。",
+	}, "\n")
+	if got != want {
+		t.Errorf("AozoraText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAozoraTextHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"区切り線あり", "羅生門\n芥川龍之介\n\n----------\n記号について\n----------\n\n本文。\n", "本文。"},
+		{"区切り線なしの表題と著者名", "羅生門\n芥川龍之介\n\n本文。\n\n底本：なし\n", "本文。"},
+		{"区切り線なしの冒頭の段落", "吾輩は猫である。\n名前はまだ無い。\n\nどこで生れたか。\n\n底本：なし\n", "吾輩は猫である。\n名前はまだ無い。\n\nどこで生れたか。"},
+		{"空行のない冒頭", "羅生門\n芥川龍之介\n本文。", "羅生門\n芥川龍之介\n本文。"},
+		{"長い冒頭", "一\n二\n三\n四\n\n本文。", "一\n二\n三\n四\n\n本文。"},
+		{"見出しのブロック", "［＃ここから大見出し］\n第一章\n［＃ここで大見出し終わり］\n本文。\n［＃本文終わり］\n後書き", "第一章。\n本文。"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AozoraText(tt.src, AozoraOptions{}); got != tt.want {
+				t.Errorf("AozoraText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateSegmentsAozora(t *testing.T) {
+	sjis, err := japanese.ShiftJIS.NewEncoder().String(aozoraSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "rashomon.txt")
+	if err := os.WriteFile(path, []byte(sjis), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultInputOptions()
+	opts.Format = FormatAozora
+	l := 40
+	segments, err := CreateSegmentsWith(path, &l, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := ParseSegments(segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts, readings []string
+	for _, line := range lines {
+		texts = append(texts, line.Text)
+		readings = append(readings, line.Reading)
+	}
+	if want := []string{"一。", "", "ある日の暮方の事である。", "一人の下人が、", "羅生門の下で雨やみを待っていた。"}; !reflect.DeepEqual(texts[:5], want) {
+		t.Errorf("texts = %q, want %q", texts[:5], want)
+	}
+	if readings[3] != "一人のげにんが、" {
+		t.Errorf("reading = %q", readings[3])
+	}
+	// 見出しの後と改ページの位置は無音行になる
+	var pauses []time.Duration
+	for _, line := range lines {
+		if line.Text == "" && line.Prosody.Pause != nil {
+			pauses = append(pauses, *line.Prosody.Pause)
+		}
+	}
+	if want := []time.Duration{DefaultHeadingPause, DefaultPagePause}; !reflect.DeepEqual(pauses, want) {
+		t.Errorf("pauses = %v, want %v", pauses, want)
+	}
+}
+
+func TestParseInputFormat(t *testing.T) {
+	for _, s := range []string{"", "auto", "text", "markdown", "aozora"} {
+		if _, err := ParseInputFormat(s); err != nil {
+			t.Errorf("ParseInputFormat(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseInputFormat("html"); err == nil {
+		t.Error("不明な形式はエラーになるはず")
+	}
+}
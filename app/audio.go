@@ -1,170 +1,185 @@
 package app
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-	"sort"
+	"time"
+	"voicevox/wav"
 )
 
-// ConcatAllWavFiles は指定されたディレクトリ内の音声ファイルを結合し、1つのファイルに保存します。
-func ConcatAllWavFiles(dir string, id string) error {
-	// ディレクトリ内のファイルを取得
-	audios := filepath.Join(dir, "*.wav")
-	files, err := filepath.Glob(audios)
-	if err != nil {
-		return fmt.Errorf("error reading directory: %v", err)
-	}
+// EngineFormat はVOICEVOXエンジンの既定の出力形式(24kHz モノラル 16bit)です。
+var EngineFormat = wav.Format{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 24000, BitsPerSample: 16}
 
-	// ファイル名をソート
-	sort.Strings(files)
+// 無音行の既定の長さ。以前の asset/silent_N.wav(0.4秒) と asset/silent_L.wav(0.7秒) に合わせている。
+const (
+	DefaultSentencePause  = 400 * time.Millisecond
+	DefaultParagraphPause = 700 * time.Millisecond
+)
 
-	// 出力ファイルを作成
-	outputFile, err := os.Create(fmt.Sprintf("out/%s.wav", id))
-	if err != nil {
-		return fmt.Errorf("error creating output file: %v", err)
+// SilenceOptions は無音行に挿入する無音の設定です。
+type SilenceOptions struct {
+	// Sentence は文の区切り(句読点のみの行)の無音の長さです。
+	Sentence time.Duration
+	// Paragraph は段落の区切り(空行)の無音の長さです。
+	Paragraph time.Duration
+	// Format は生成する無音の形式です。合成される音声と同じ形式にしておきます。
+	Format wav.Format
+}
+
+// DefaultSilenceOptions は既定の無音の設定を返します。
+func DefaultSilenceOptions() SilenceOptions {
+	return SilenceOptions{
+		Sentence:  DefaultSentencePause,
+		Paragraph: DefaultParagraphPause,
+		Format:    EngineFormat,
 	}
-	defer outputFile.Close()
+}
 
-	var totalDataSize int64
+// Duration は無音行 line に挿入する無音の長さを返します。
+// 行頭タグで pause が指定されている場合はその長さを使います。
+func (o SilenceOptions) Duration(line ScriptLine) time.Duration {
+	if line.Prosody.Pause != nil {
+		return *line.Prosody.Pause
+	}
+	if line.Break == BreakSentence {
+		return o.Sentence
+	}
+	return o.Paragraph
+}
 
-	// 各ファイルを結合
-	for i, file := range files {
-		err = appendFile(outputFile, file, i == 0)
-		if err != nil {
-			return fmt.Errorf("error appending file %s: %v", file, err)
-		}
+// Synthesizer は台本の各行を音声(WAV)に変換します。
+type Synthesizer struct {
+	Client  *Client
+	Silence SilenceOptions
+	// Cache が nil でない場合、内容が同じ行は再合成せずに保存済みの音声を使います。
+	// 鍵に EngineVersion と DictionaryHash を使うため、LoadEngineState を呼んでおく必要があります。
+	Cache *Cache
+	// Retry はエンジンへのリクエストが一時的に失敗した場合の再試行の設定です。
+	Retry RetryPolicy
+	// Normalizer が nil でない場合、行の本文を読み上げやすい形に変換してからエンジンに送ります。
+	Normalizer *TextNormalizer
+	// EngineVersion はエンジンのバージョン、DictionaryHash はエンジンのユーザー辞書の内容のハッシュです。
+	// 保存済みの音声を再利用できるかの判定に使い、LoadEngineState で設定します。
+	EngineVersion  string
+	DictionaryHash string
+}
 
-		// ファイルサイズを取得してデータサイズを更新
-		fileInfo, err := os.Stat(file)
-		if err != nil {
-			return fmt.Errorf("error getting file info: %v", err)
-		}
+// NewSynthesizer は既定の無音、再試行、テキストの変換の設定で client を使う Synthesizer を生成します。
+func NewSynthesizer(client *Client) *Synthesizer {
+	return &Synthesizer{Client: client, Silence: DefaultSilenceOptions(), Retry: DefaultRetryPolicy(), Normalizer: DefaultTextNormalizer()}
+}
 
-		// 最初のファイルはヘッダーを含むので、44バイトを引く
-		if i == 0 {
-			totalDataSize += fileInfo.Size()
-		} else {
-			totalDataSize += fileInfo.Size() - 44
-		}
+// LoadEngineState はエンジンのバージョンとユーザー辞書を取得して EngineVersion と DictionaryHash に設定します。
+// ユーザー辞書を更新した場合は、音声合成の前にもう一度呼んでおく必要があります。
+func (s *Synthesizer) LoadEngineState(ctx context.Context) error {
+	version, err := s.Client.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("エンジンのバージョンの取得に失敗しました: %w", err)
 	}
-
-	// 出力ファイルのWAVヘッダーを更新
-	err = updateWavHeader(outputFile, totalDataSize)
+	dict, err := ExportDictionary(ctx, s.Client)
 	if err != nil {
-		return fmt.Errorf("error updating WAV header: %v", err)
+		return fmt.Errorf("ユーザー辞書の取得に失敗しました: %w", err)
 	}
-
-	fmt.Println("All audio files have been concatenated successfully.")
+	s.EngineVersion, s.DictionaryHash = version, dict.Hash()
 	return nil
 }
 
-// appendFile は指定されたファイルを出力ファイルに追加します。
-// 最初のファイルの場合はヘッダーを含め、以降のファイルはデータ部分のみを追加します。
-func appendFile(outputFile *os.File, inputFilename string, includeHeader bool) error {
-	inputFile, err := os.Open(inputFilename)
-	if err != nil {
-		return fmt.Errorf("error opening input file: %v", err)
+// text は line の本文のうち、エンジンに送るテキストを返します。ルビがある場合は読み方を使います。
+func (s *Synthesizer) text(line ScriptLine) string {
+	text := line.Text
+	if line.Reading != "" {
+		text = line.Reading
+	}
+	if s.Normalizer == nil {
+		return text
 	}
-	defer inputFile.Close()
+	return s.Normalizer.Normalize(text)
+}
 
-	if includeHeader {
-		// 最初のファイルのヘッダーを出力ファイルに書き込む
-		_, err = io.Copy(outputFile, inputFile)
-		if err != nil {
-			return fmt.Errorf("error copying data: %v", err)
-		}
-	} else {
-		// ヘッダーをスキップしてデータ部分のみをコピー
-		_, err = inputFile.Seek(44, io.SeekStart)
-		if err != nil {
-			return fmt.Errorf("error seeking input file: %v", err)
-		}
-		_, err = io.Copy(outputFile, inputFile)
+// Synthesize は台本の1行を line.SpeakerID の声で音声合成し、WAVファイルのバイト列を返します。
+// 行頭タグで指定された韻律はクエリに反映してから合成し、無音行は無音を生成します。
+func (s *Synthesizer) Synthesize(ctx context.Context, line ScriptLine) ([]byte, error) {
+	data, _, err := s.SynthesizeQuery(ctx, line)
+	return data, err
+}
+
+// SynthesizeQuery は Synthesize と同じく音声合成し、合成に使った音声クエリも返します。
+// 無音行の場合、クエリは nil です。
+func (s *Synthesizer) SynthesizeQuery(ctx context.Context, line ScriptLine) ([]byte, *AudioQuery, error) {
+	// 無音行かどうかを判定する
+	if line.Text == "" {
+		var buf bytes.Buffer
+		err := wav.Encode(&buf, wav.Silence(s.Silence.Format, s.Silence.Duration(line)))
 		if err != nil {
-			return fmt.Errorf("error copying data: %v", err)
+			return nil, nil, fmt.Errorf("無音の生成中にエラーが発生しました: %w", err)
 		}
+		return buf.Bytes(), nil, nil
 	}
 
-	return nil
-}
+	// 同じ内容の行を合成済みであれば、エンジンに問い合わせずにそれを使う
+	var key string
+	if s.Cache != nil {
+		key = s.Cache.Key(s.signature(line))
+		if data, qa, ok := s.Cache.Get(key); ok {
+			return data, qa, nil
+		}
+	}
 
-// updateWavHeader は出力ファイルのWAVヘッダーを更新して、正しいデータサイズを反映させます。
-func updateWavHeader(outputFile *os.File, dataSize int64) error {
-	// RIFFチャンクのサイズを更新
-	_, err := outputFile.Seek(4, io.SeekStart)
+	qa, err := s.Query(ctx, line)
 	if err != nil {
-		return fmt.Errorf("error seeking output file: %v", err)
+		return nil, nil, err
 	}
-	_, err = outputFile.Write([]byte{
-		byte(dataSize + 36), byte((dataSize + 36) >> 8),
-		byte((dataSize + 36) >> 16), byte((dataSize + 36) >> 24),
+
+	// 音声を合成
+	var audioData []byte
+	err = s.Retry.Do(ctx, func() (err error) {
+		audioData, err = s.Client.Synthesize(ctx, qa, line.SpeakerID)
+		return err
 	})
 	if err != nil {
-		return fmt.Errorf("error writing RIFF size: %v", err)
+		return nil, nil, fmt.Errorf("音声合成中にエラーが発生しました: %w", err)
 	}
-
-	// dataチャンクのサイズを更新
-	_, err = outputFile.Seek(40, io.SeekStart)
-	if err != nil {
-		return fmt.Errorf("error seeking output file: %v", err)
+	if s.Cache != nil {
+		if err := s.Cache.Put(key, audioData, qa); err != nil {
+			return nil, nil, err
+		}
 	}
-	_, err = outputFile.Write([]byte{
-		byte(dataSize), byte(dataSize >> 8),
-		byte(dataSize >> 16), byte(dataSize >> 24),
+	return audioData, qa, nil
+}
+
+// Query は台本の1行の音声クエリを生成し、行頭タグで指定された韻律を反映して返します。
+func (s *Synthesizer) Query(ctx context.Context, line ScriptLine) (*AudioQuery, error) {
+	var qa *AudioQuery
+	err := s.Retry.Do(ctx, func() (err error) {
+		qa, err = s.Client.Audio(ctx, s.text(line), line.SpeakerID)
+		return err
 	})
 	if err != nil {
-		return fmt.Errorf("error writing data size: %v", err)
+		return nil, fmt.Errorf("音声クエリの生成中にエラーが発生しました: %w", err)
 	}
-
-	return nil
+	line.Prosody.Apply(qa)
+	return qa, nil
 }
 
-func GenerateAndSaveAudio(text string, speakerID int, outputPath string) error {
-	// 無音行かどうかを判定する
-	// 無音行はtextの行開始が"..."で始まるかで判定する。
-	var audioData []byte
-	if text == "" {
-		silentFile, err := os.Open("../../../asset/silent_N.wav")
-		if err != nil {
-			return fmt.Errorf("無音ファイルを開く際にエラーが発生しました: %v", err)
-		}
-		defer silentFile.Close()
-		// silentFileをbyte配列に変換
-		audioData, err = io.ReadAll(silentFile)
-		if err != nil {
-			return fmt.Errorf("無音ファイルを読み込む際にエラーが発生しました: %v", err)
-		}
-	} else {
-		// 音声合成用のクエリを生成
-		qa, err := Audio(text, speakerID)
-		if err != nil {
-			return fmt.Errorf("音声クエリの生成中にエラーが発生しました: %v", err)
-		}
-
-		// 音声を合成
-		audioData, err = Synthesize(qa, speakerID)
-		if err != nil {
-			return fmt.Errorf("音声合成中にエラーが発生しました: %v", err)
-		}
-	}
-
-	// 音声ファイルを保存
-	// outディレクトリを作成
-	err := os.MkdirAll("out", os.ModePerm)
+// GenerateAndSaveAudio は台本の1行を synth で音声合成し、outputPath に保存します。
+func GenerateAndSaveAudio(ctx context.Context, synth *Synthesizer, line ScriptLine, outputPath string) error {
+	audioData, err := synth.Synthesize(ctx, line)
 	if err != nil {
-		return fmt.Errorf("outディレクトリの作成中にエラーが発生しました: %v", err)
+		return err
 	}
 
-	// プロジェクトルートのoutディレクトリにoutput.wavとして保存
-	file, err := os.Create(outputPath)
+	// 音声ファイルを保存
+	// 保存先のディレクトリを作成
+	err = os.MkdirAll(filepath.Dir(outputPath), os.ModePerm)
 	if err != nil {
-		return fmt.Errorf("音声ファイルの作成中にエラーが発生しました: %v", err)
+		return fmt.Errorf("出力ディレクトリの作成中にエラーが発生しました: %v", err)
 	}
-	defer file.Close()
 
-	_, err = file.Write(audioData)
+	// 中断しても書きかけのファイルが残らないよう、一時ファイルに書いてから名前を変える
+	err = writeFileAtomic(outputPath, audioData)
 	if err != nil {
 		return fmt.Errorf("音声データをファイルに書き込む際にエラーが発生しました: %v", err)
 	}
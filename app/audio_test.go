@@ -0,0 +1,38 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+	"voicevox/wav"
+)
+
+func TestSynthesizeSilence(t *testing.T) {
+	synth := NewSynthesizer(nil)
+	synth.Silence.Sentence = 200 * time.Millisecond
+	synth.Silence.Paragraph = time.Second
+
+	script, err := ParseSegments([]Segment{
+		{Break: BreakSentence},
+		{Break: BreakParagraph},
+		{Text: "[pause=1.5s]"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{200 * time.Millisecond, time.Second, 1500 * time.Millisecond}
+	for i, line := range script {
+		data, err := synth.Synthesize(context.Background(), line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := wav.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Format != EngineFormat || f.Duration() != want[i] {
+			t.Errorf("%d行目: got %v %v, want %v", i+1, f.Format, f.Duration(), want[i])
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+// Mora はアクセント句を構成する1モーラです。
+// 子音を持たないモーラでは Consonant と ConsonantLength が nil になります。
+type Mora struct {
+	Text            string   `json:"text"`
+	Consonant       *string  `json:"consonant"`
+	ConsonantLength *float64 `json:"consonant_length"`
+	Vowel           string   `json:"vowel"`
+	VowelLength     float64  `json:"vowel_length"`
+	Pitch           float64  `json:"pitch"`
+}
+
+// AccentPhrase はアクセント句です。
+// PauseMora は句の後ろに無音が入る場合のみ設定されます。
+type AccentPhrase struct {
+	Moras           []Mora `json:"moras"`
+	Accent          int    `json:"accent"`
+	PauseMora       *Mora  `json:"pause_mora"`
+	IsInterrogative bool   `json:"is_interrogative"`
+}
+
+// AudioQuery はエンジンの audio_query が返す音声合成用のクエリです。
+// 値を書き換えてから Client.Synthesize に渡すことで、話速や音高を調整できます。
+type AudioQuery struct {
+	AccentPhrases      []AccentPhrase `json:"accent_phrases"`
+	SpeedScale         float64        `json:"speedScale"`
+	PitchScale         float64        `json:"pitchScale"`
+	IntonationScale    float64        `json:"intonationScale"`
+	VolumeScale        float64        `json:"volumeScale"`
+	PrePhonemeLength   float64        `json:"prePhonemeLength"`
+	PostPhonemeLength  float64        `json:"postPhonemeLength"`
+	PauseLength        *float64       `json:"pauseLength,omitempty"`
+	PauseLengthScale   *float64       `json:"pauseLengthScale,omitempty"`
+	OutputSamplingRate int            `json:"outputSamplingRate"`
+	OutputStereo       bool           `json:"outputStereo"`
+	Kana               string         `json:"kana,omitempty"`
+}
+
+// EachMora は全てのアクセント句のモーラ(ポーズを除く)に対して f を呼び出します。
+// f に渡されるポインタを書き換えるとクエリに反映されます。
+func (q *AudioQuery) EachMora(f func(m *Mora)) {
+	for i := range q.AccentPhrases {
+		for j := range q.AccentPhrases[i].Moras {
+			f(&q.AccentPhrases[i].Moras[j])
+		}
+	}
+}
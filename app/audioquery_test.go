@@ -0,0 +1,80 @@
+package app
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// engineAudioQuery はエンジンが返す audio_query レスポンスの例です。
+const engineAudioQuery = `{
+  "accent_phrases": [
+    {
+      "moras": [
+        {"text": "コ", "consonant": "k", "consonant_length": 0.0556, "vowel": "o", "vowel_length": 0.0887, "pitch": 5.78},
+        {"text": "ン", "consonant": null, "consonant_length": null, "vowel": "N", "vowel_length": 0.0718, "pitch": 5.91}
+      ],
+      "accent": 5,
+      "pause_mora": {"text": "、", "consonant": null, "consonant_length": null, "vowel": "pau", "vowel_length": 0.31, "pitch": 0},
+      "is_interrogative": false
+    }
+  ],
+  "speedScale": 1,
+  "pitchScale": 0,
+  "intonationScale": 1,
+  "volumeScale": 1,
+  "prePhonemeLength": 0.1,
+  "postPhonemeLength": 0.1,
+  "outputSamplingRate": 24000,
+  "outputStereo": false,
+  "kana": "コ'ン、"
+}`
+
+func TestAudioQueryJSON(t *testing.T) {
+	var q AudioQuery
+	if err := json.Unmarshal([]byte(engineAudioQuery), &q); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.AccentPhrases) != 1 || len(q.AccentPhrases[0].Moras) != 2 {
+		t.Fatalf("unexpected accent phrases: %+v", q.AccentPhrases)
+	}
+	if m := q.AccentPhrases[0].Moras[1]; m.Consonant != nil || m.ConsonantLength != nil {
+		t.Errorf("モーラ「ン」は子音を持たないはず: %+v", m)
+	}
+	if q.AccentPhrases[0].PauseMora == nil || q.AccentPhrases[0].PauseMora.Vowel != "pau" {
+		t.Errorf("pause_mora が読み込まれていない: %+v", q.AccentPhrases[0].PauseMora)
+	}
+	if q.OutputSamplingRate != 24000 || q.Kana != "コ'ン、" {
+		t.Errorf("unexpected query: %+v", q)
+	}
+
+	// 再エンコードしても元のJSONと同じ内容になること
+	data, err := json.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, got map[string]any
+	if err := json.Unmarshal([]byte(engineAudioQuery), &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("round trip mismatch:\nwant %v\ngot  %v", want, got)
+	}
+}
+
+func TestAudioQueryEachMora(t *testing.T) {
+	var q AudioQuery
+	if err := json.Unmarshal([]byte(engineAudioQuery), &q); err != nil {
+		t.Fatal(err)
+	}
+	q.EachMora(func(m *Mora) { m.Pitch += 0.1 })
+	if got := q.AccentPhrases[0].Moras[0].Pitch; got != 5.78+0.1 {
+		t.Errorf("pitch = %v", got)
+	}
+	if got := q.AccentPhrases[0].PauseMora.Pitch; got != 0 {
+		t.Errorf("pause_mora は変更されないはず: %v", got)
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// cacheFormat は鍵の計算方法や保存形式を変えた場合に上げる版数です。古いキャッシュは使われなくなります。
+const cacheFormat = 2
+
+// Cache は合成した音声と音声クエリを、音声の内容を決める値(エンジンに送るテキスト、話者、韻律、
+// エンジンのバージョンとユーザー辞書)から計算した鍵で保存します。
+// 内容が同じ行はエンジンに問い合わせずに保存済みの音声を使います。
+//
+// 保存先は <Dir>/<鍵の先頭2文字>/<鍵>.wav と、音声クエリの <鍵>.json です。複数のゴルーチンから同時に使えます。
+type Cache struct {
+	Dir string
+
+	hits, misses atomic.Int64
+}
+
+// NewCache は dir に音声を保存する Cache を生成します。
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("キャッシュディレクトリの作成に失敗しました: %w", err)
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+// DefaultCacheDir はユーザーのキャッシュディレクトリの下の voicebox ディレクトリを返します。
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "voicebox"), nil
+}
+
+// Key は音声の内容を決める値 signature(Synthesizer.signature)から鍵を計算します。
+func (c *Cache) Key(signature string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", cacheFormat, signature)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key, ext string) string {
+	return filepath.Join(c.Dir, key[:2], key+ext)
+}
+
+// Get は key の音声と音声クエリを返します。保存されていない場合は ok が false になります。
+func (c *Cache) Get(key string) (data []byte, query *AudioQuery, ok bool) {
+	data, err := os.ReadFile(c.path(key, ".wav"))
+	if err == nil {
+		var q []byte
+		if q, err = os.ReadFile(c.path(key, ".json")); err == nil {
+			err = json.Unmarshal(q, &query)
+		}
+	}
+	if err != nil {
+		c.misses.Add(1)
+		return nil, nil, false
+	}
+	c.hits.Add(1)
+	return data, query, true
+}
+
+// Put は key の音声と音声クエリを保存します。
+// 一時ファイルに書き込んでから名前を変え、音声を最後に保存するため、中断しても壊れたキャッシュは残りません。
+func (c *Cache) Put(key string, data []byte, query *AudioQuery) error {
+	q, err := json.Marshal(query)
+	if err != nil {
+		return fmt.Errorf("音声クエリの変換に失敗しました: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(c.Dir, key[:2]), os.ModePerm); err != nil {
+		return fmt.Errorf("キャッシュディレクトリの作成に失敗しました: %w", err)
+	}
+	if err := writeFileAtomic(c.path(key, ".json"), q); err != nil {
+		return fmt.Errorf("キャッシュの保存に失敗しました: %w", err)
+	}
+	if err := writeFileAtomic(c.path(key, ".wav"), data); err != nil {
+		return fmt.Errorf("キャッシュの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// Stats は Get で保存済みの音声を使えた数と使えなかった数を返します。
+func (c *Cache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// writeFileAtomic は同じディレクトリの一時ファイルに data を書き込んでから path に名前を変えます。
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // 名前を変えた後は何もしない
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
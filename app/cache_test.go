@@ -0,0 +1,168 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"voicevox/wav"
+)
+
+// fakeEngine は audio_query, synthesis, version, user_dict の取得だけを実装したエンジンの代わりのサーバーです。
+type fakeEngine struct {
+	*httptest.Server
+	version   string
+	userDict  map[string]UserDictWord
+	queries   atomic.Int64
+	syntheses atomic.Int64
+
+	// failures が正の間は synthesis が failStatus を返します。
+	failures   atomic.Int64
+	failStatus int
+
+	// delay が nil でない場合、audio_query は delay(text) だけ待ってから応答します。
+	delay func(text string) time.Duration
+}
+
+func newFakeEngine(t *testing.T) *fakeEngine {
+	t.Helper()
+	e := &fakeEngine{version: "0.14.0"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(e.version)
+	})
+	mux.HandleFunc("GET /user_dict", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(e.userDict)
+	})
+	mux.HandleFunc("POST /audio_query", func(w http.ResponseWriter, r *http.Request) {
+		e.queries.Add(1)
+		if e.delay != nil {
+			select {
+			case <-time.After(e.delay(r.URL.Query().Get("text"))):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(AudioQuery{SpeedScale: 1, VolumeScale: 1, IntonationScale: 1, OutputSamplingRate: 24000, Kana: r.URL.Query().Get("text")})
+	})
+	mux.HandleFunc("POST /synthesis", func(w http.ResponseWriter, r *http.Request) {
+		if e.failures.Add(-1) >= 0 {
+			http.Error(w, `{"detail":"failed"}`, e.failStatus)
+			return
+		}
+		e.syntheses.Add(1)
+		var buf bytes.Buffer
+		wav.Encode(&buf, wav.Silence(EngineFormat, 100*time.Millisecond))
+		w.Write(buf.Bytes())
+	})
+	e.Server = httptest.NewServer(mux)
+	t.Cleanup(e.Close)
+	return e
+}
+
+func (e *fakeEngine) client(t *testing.T) *Client {
+	t.Helper()
+	c, err := NewClient(e.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSynthesizeCache(t *testing.T) {
+	engine := newFakeEngine(t)
+	client := engine.client(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	newSynth := func() *Synthesizer {
+		synth := NewSynthesizer(client)
+		if err := synth.LoadEngineState(ctx); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		synth.Cache, err = NewCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return synth
+	}
+	line := func(s string, id int) ScriptLine {
+		l, err := ParseScriptLine(1, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.SpeakerID = id
+		return l
+	}
+
+	synth := newSynth()
+	steps := []struct {
+		name string
+		line ScriptLine
+		// synthesized は合成が行われるはずかどうかです
+		synthesized bool
+	}{
+		{name: "初回", line: line("こんにちは。", 1), synthesized: true},
+		{name: "同じ行", line: line("こんにちは。", 1), synthesized: false},
+		{name: "本文の変更", line: line("こんばんは。", 1), synthesized: true},
+		{name: "話者の変更", line: line("こんにちは。", 3), synthesized: true},
+		{name: "韻律の変更", line: line("[speed=1.2] こんにちは。", 1), synthesized: true},
+		{name: "無音行はキャッシュしない", line: ScriptLine{Break: BreakParagraph}, synthesized: false},
+	}
+	for _, s := range steps {
+		before, beforeQueries := engine.syntheses.Load(), engine.queries.Load()
+		data, query, err := synth.SynthesizeQuery(ctx, s.line)
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if _, err := wav.Decode(bytes.NewReader(data)); err != nil {
+			t.Errorf("%s: %v", s.name, err)
+		}
+		if got := engine.syntheses.Load() > before; got != s.synthesized {
+			t.Errorf("%s: synthesized = %v, want %v", s.name, got, s.synthesized)
+		}
+		// 保存済みの音声を使う場合は音声クエリも保存済みのものを使い、エンジンに問い合わせない
+		if got := engine.queries.Load() > beforeQueries; got != s.synthesized {
+			t.Errorf("%s: queried = %v, want %v", s.name, got, s.synthesized)
+		}
+		if s.line.Text != "" && (query == nil || query.Kana != synth.text(s.line)) {
+			t.Errorf("%s: query = %+v", s.name, query)
+		}
+	}
+	if hits, misses := synth.Cache.Stats(); hits != 1 || misses != 4 {
+		t.Errorf("stats = %d hits, %d misses", hits, misses)
+	}
+
+	// エンジンのバージョンやユーザー辞書が変わると再合成する
+	changes := []struct {
+		name   string
+		change func()
+	}{
+		{name: "エンジンのバージョン", change: func() { engine.version = "0.15.0" }},
+		{name: "ユーザー辞書", change: func() {
+			engine.userDict = map[string]UserDictWord{"id": {Surface: "ｃｏｎｎｉｃｈｉｈａ", Pronunciation: "コンニチハ", Priority: 5}}
+		}},
+	}
+	for _, c := range changes {
+		c.change()
+		before := engine.syntheses.Load()
+		if _, err := newSynth().Synthesize(ctx, line("こんにちは。", 1)); err != nil {
+			t.Fatal(err)
+		}
+		if engine.syntheses.Load() == before {
+			t.Errorf("%sが変わった場合は再合成するはず", c.name)
+		}
+	}
+
+	// 全ての行が保存済みであればエンジンが止まっていても合成できる
+	synth = newSynth()
+	engine.Close()
+	if _, err := synth.Synthesize(ctx, line("こんにちは。", 1)); err != nil {
+		t.Errorf("保存済みの音声を使うはず: %v", err)
+	}
+}
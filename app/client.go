@@ -0,0 +1,265 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEngineURL はVOICEVOXエンジンの既定の接続先です。
+const DefaultEngineURL = "http://localhost:50021"
+
+// EngineURLEnv は接続先エンジンのURLを上書きする環境変数名です。
+const EngineURLEnv = "VOICEVOX_ENGINE_URL"
+
+// DefaultTimeout はエンジンへの1リクエストあたりの既定のタイムアウトです。
+const DefaultTimeout = 60 * time.Second
+
+var (
+	// ErrEngineUnreachable はエンジンに接続できなかった場合に返されます。
+	ErrEngineUnreachable = errors.New("VOICEVOXエンジンに接続できません")
+	// ErrEngineTimeout はエンジンの応答がクライアントのタイムアウトまでに返らなかった場合に返されます。
+	ErrEngineTimeout = errors.New("VOICEVOXエンジンの応答がタイムアウトしました")
+	// ErrUnknownSpeaker は指定した話者IDがエンジンに存在しない場合に返されます。
+	ErrUnknownSpeaker = errors.New("指定された話者が見つかりません")
+)
+
+// ValidationDetail はエンジンが422で返すdetail配列の1要素です。
+type ValidationDetail struct {
+	Loc  []any  `json:"loc"`
+	Msg  string `json:"msg"`
+	Type string `json:"type"`
+}
+
+// ValidationError はエンジンがリクエストを検証エラー(422)として拒否した場合に返されます。
+type ValidationError struct {
+	Detail []ValidationDetail
+	// Body はエンジンが返したレスポンスボディそのものです。
+	Body []byte
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Detail) == 0 {
+		return fmt.Sprintf("リクエストの検証に失敗しました: %s", e.Body)
+	}
+	msgs := make([]string, 0, len(e.Detail))
+	for _, d := range e.Detail {
+		loc := make([]string, 0, len(d.Loc))
+		for _, l := range d.Loc {
+			loc = append(loc, fmt.Sprint(l))
+		}
+		if len(loc) == 0 {
+			msgs = append(msgs, d.Msg)
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("%s: %s", strings.Join(loc, "."), d.Msg))
+	}
+	return fmt.Sprintf("リクエストの検証に失敗しました: %s", strings.Join(msgs, "; "))
+}
+
+// StatusError はエンジンが上記以外の失敗ステータスを返した場合に返されます。
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("エンジンがステータス %d を返しました: %s", e.StatusCode, bytes.TrimSpace(e.Body))
+}
+
+// Client はVOICEVOXエンジンのHTTP APIクライアントです。
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// NewClient は baseURL のエンジンに接続するクライアントを生成します。
+// baseURL が空の場合は環境変数 VOICEVOX_ENGINE_URL、それも無ければ DefaultEngineURL を使います。
+// timeout が0以下の場合は DefaultTimeout を使います。
+func NewClient(baseURL string, timeout time.Duration) (*Client, error) {
+	if baseURL == "" {
+		baseURL = EngineURL()
+	}
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("エンジンのURLが不正です: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("エンジンのURLが不正です: %q", baseURL)
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// EngineURL は環境変数を考慮した既定の接続先を返します。
+func EngineURL() string {
+	if v := strings.TrimSpace(os.Getenv(EngineURLEnv)); v != "" {
+		return v
+	}
+	return DefaultEngineURL
+}
+
+// BaseURL は接続先エンジンのURLを返します。
+func (c *Client) BaseURL() string {
+	return c.baseURL.String()
+}
+
+// Audio はテキストから音声合成用のクエリ(audio_query)を生成します。
+func (c *Client) Audio(ctx context.Context, text string, speakerID int) (*AudioQuery, error) {
+	q := url.Values{}
+	q.Set("text", text)
+	q.Set("speaker", strconv.Itoa(speakerID))
+	data, err := c.do(ctx, http.MethodPost, "/audio_query", q, nil, speakerID)
+	if err != nil {
+		return nil, err
+	}
+	var query AudioQuery
+	if err := json.Unmarshal(data, &query); err != nil {
+		return nil, fmt.Errorf("音声クエリの解析に失敗しました: %w", err)
+	}
+	return &query, nil
+}
+
+// Synthesize はクエリから音声(WAV)を合成します。
+func (c *Client) Synthesize(ctx context.Context, query *AudioQuery, speakerID int) ([]byte, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("音声クエリの変換に失敗しました: %w", err)
+	}
+	q := url.Values{}
+	q.Set("speaker", strconv.Itoa(speakerID))
+	return c.do(ctx, http.MethodPost, "/synthesis", q, body, speakerID)
+}
+
+// Version はエンジンのバージョンを取得します。
+func (c *Client) Version(ctx context.Context) (string, error) {
+	data, err := c.do(ctx, http.MethodGet, "/version", nil, nil, -1)
+	if err != nil {
+		return "", err
+	}
+	var version string
+	if err := json.Unmarshal(data, &version); err != nil {
+		return "", fmt.Errorf("バージョンの解析に失敗しました: %w", err)
+	}
+	return version, nil
+}
+
+// do はリクエストを送信し、成功ステータスであればボディを返します。
+// speakerID が0以上の場合、404は ErrUnknownSpeaker として扱います。
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte, speakerID int) ([]byte, error) {
+	u := *c.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, fmt.Errorf("リクエストの作成に失敗しました: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if err := c.timeoutError(ctx, err); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w (%s): %w", ErrEngineUnreachable, c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if err := c.timeoutError(ctx, err); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("レスポンスの読み込みに失敗しました: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return data, nil
+	}
+	return nil, responseError(resp.StatusCode, data, speakerID)
+}
+
+// timeoutError は通信のエラー err がキャンセルかタイムアウトによるものであればそのエラーを返し、そうでなければ nil を返します。
+// ctx がキャンセルされた場合や期限を過ぎた場合は ctx のエラーをそのまま返し、
+// クライアントのタイムアウトの場合は ErrEngineTimeout として返します(context.DeadlineExceeded も含みます)。
+func (c *Client) timeoutError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &nerr) && nerr.Timeout()) {
+		return fmt.Errorf("%w (%s, %s): %w", ErrEngineTimeout, c.baseURL, c.httpClient.Timeout, err)
+	}
+	return nil
+}
+
+// responseError は失敗ステータスのレスポンスを型付きのエラーに変換します。
+func responseError(status int, body []byte, speakerID int) error {
+	switch {
+	case status == http.StatusNotFound && speakerID >= 0:
+		return fmt.Errorf("%w: speaker=%d: %s", ErrUnknownSpeaker, speakerID, engineDetail(body))
+	case status == http.StatusUnprocessableEntity:
+		var v struct {
+			Detail json.RawMessage `json:"detail"`
+		}
+		verr := &ValidationError{Body: body}
+		if json.Unmarshal(body, &v) == nil {
+			if json.Unmarshal(v.Detail, &verr.Detail) != nil {
+				// detailが文字列の場合(話者が存在しない等)
+				var msg string
+				if json.Unmarshal(v.Detail, &msg) == nil {
+					verr.Detail = []ValidationDetail{{Msg: msg}}
+					if speakerID >= 0 && mentionsSpeaker(msg, speakerID) {
+						return fmt.Errorf("%w: speaker=%d: %w", ErrUnknownSpeaker, speakerID, verr)
+					}
+				}
+			}
+		}
+		return verr
+	default:
+		return &StatusError{StatusCode: status, Body: body}
+	}
+}
+
+// mentionsSpeaker はエンジンのエラーメッセージ msg が話者・スタイル(または speakerID)についてのものかを返します。
+func mentionsSpeaker(msg string, speakerID int) bool {
+	lower := strings.ToLower(msg)
+	for _, w := range []string{"speaker", "style", "話者", "スタイル", strconv.Itoa(speakerID)} {
+		if strings.Contains(lower, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// engineDetail はエンジンのエラーレスポンスからdetailの文字列を取り出します。
+func engineDetail(body []byte) string {
+	var v struct {
+		Detail string `json:"detail"`
+	}
+	if json.Unmarshal(body, &v) == nil && v.Detail != "" {
+		return v.Detail
+	}
+	return string(bytes.TrimSpace(body))
+}
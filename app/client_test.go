@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAudio(t *testing.T) {
+	type args struct {
+		text      string
+		speakerID int
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{
+			name: "Test Empty Wav Generation",
+			args: args{text: "、、、、、、、、、、", speakerID: 1},
+		},
+	}
+	client, err := NewClient("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.Audio(ctx, tt.args.text, tt.args.speakerID)
+			if errors.Is(err, ErrEngineUnreachable) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Log(err)
+				t.Fail()
+			}
+			if got == nil {
+				t.Log("Empty query")
+				t.Fail()
+			}
+			wav, err := client.Synthesize(ctx, got, tt.args.speakerID)
+			if err != nil {
+				t.Log(err)
+				t.Fail()
+			}
+			// wavをファイル保存
+			err = SaveFile(wav, "./test_audio.wav")
+			if err != nil {
+				t.Log(err)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name:   "正常系",
+			status: http.StatusOK,
+			body:   `{"accent_phrases":[]}`,
+			check: func(t *testing.T, err error) {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name:   "存在しない話者",
+			status: http.StatusNotFound,
+			body:   `{"detail":"該当する話者が見つかりません"}`,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, ErrUnknownSpeaker) {
+					t.Errorf("expected ErrUnknownSpeaker, got %v", err)
+				}
+			},
+		},
+		{
+			name:   "検証エラー",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"detail":[{"loc":["query","speaker"],"msg":"value is not a valid integer","type":"type_error.integer"}]}`,
+			check: func(t *testing.T, err error) {
+				var verr *ValidationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("expected *ValidationError, got %v", err)
+				}
+				if len(verr.Detail) != 1 || verr.Detail[0].Type != "type_error.integer" {
+					t.Errorf("unexpected detail: %+v", verr.Detail)
+				}
+			},
+		},
+		{
+			name:   "存在しないスタイル(detailが文字列)",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"detail":"該当するスタイル(style_id=3)が見つかりません"}`,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, ErrUnknownSpeaker) {
+					t.Errorf("expected ErrUnknownSpeaker, got %v", err)
+				}
+				var verr *ValidationError
+				if !errors.As(err, &verr) || len(verr.Detail) != 1 {
+					t.Errorf("expected *ValidationError, got %v", err)
+				}
+			},
+		},
+		{
+			name:   "話者と関係のない検証エラー(detailが文字列)",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"detail":"テキストが長すぎます"}`,
+			check: func(t *testing.T, err error) {
+				if errors.Is(err, ErrUnknownSpeaker) {
+					t.Errorf("unexpected ErrUnknownSpeaker: %v", err)
+				}
+			},
+		},
+		{
+			name:   "サーバーエラー",
+			status: http.StatusInternalServerError,
+			body:   `Internal Server Error`,
+			check: func(t *testing.T, err error) {
+				var serr *StatusError
+				if !errors.As(err, &serr) || serr.StatusCode != http.StatusInternalServerError {
+					t.Errorf("expected *StatusError(500), got %v", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/audio_query" || r.URL.Query().Get("speaker") != "3" {
+					t.Errorf("unexpected request: %s", r.URL)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			client, err := NewClient(srv.URL, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.Audio(context.Background(), "テスト", 3)
+			tt.check(t, err)
+		})
+	}
+}
+
+func TestClientUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	client, err := NewClient(url, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Synthesize(context.Background(), &AudioQuery{}, 1)
+	if !errors.Is(err, ErrEngineUnreachable) {
+		t.Errorf("expected ErrEngineUnreachable, got %v", err)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(srv.URL, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// クライアントのタイムアウトは接続できない場合と区別する
+	_, err = client.Synthesize(context.Background(), &AudioQuery{}, 1)
+	if !errors.Is(err, ErrEngineTimeout) || errors.Is(err, ErrEngineUnreachable) {
+		t.Errorf("expected ErrEngineTimeout, got %v", err)
+	}
+
+	// 呼び出し側のキャンセルと期限切れはそのまま返す
+	client, err = NewClient(srv.URL, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Synthesize(ctx, &AudioQuery{}, 1); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Synthesize(ctx, &AudioQuery{}, 1); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"voicevox/wav"
+)
+
+// ConcatOptions は音声ファイルの結合方法の設定です。
+type ConcatOptions struct {
+	// Format は出力の形式です。nil の場合は先頭のファイルの形式を使います。
+	Format *wav.Format
+	// Convert が true の場合、形式の異なるファイルを出力の形式に変換(リサンプリング、
+	// チャンネル数、ビット深度の変換)してから結合します。
+	// false の場合は形式の異なるファイルがあるとエラーになります。
+	Convert bool
+
+	// Gap は全ての境界に挿入する無音の長さです。
+	Gap time.Duration
+	// PunctuationGaps は直前の行の末尾の文字("。" や "、")ごとに、Gap の代わりに挿入する無音の長さです。
+	PunctuationGaps map[string]time.Duration
+	// Crossfade は無音を挿入しない境界で前後の音声を重ねる長さです。
+	Crossfade time.Duration
+	// CrossfadeCurve はクロスフェードの形状です。
+	CrossfadeCurve wav.FadeCurve
+
+	// Normalize は結合後の音声全体の音量の正規化の設定です。
+	// 正規化を行う場合は結合した音声をメモリ上に保持してから書き出します。
+	Normalize wav.NormalizeOptions
+
+	// Subtitles はファイルに保存する場合に、音声と同じ名前で一緒に保存する字幕の形式です。
+	Subtitles []SubtitleFormat
+	// Timing はファイルに保存する場合に、音声と同じ名前で一緒に保存するモーラのタイミングの形式です。
+	// 音声クエリのある行(LineResult.Query)のモーラを含めます。
+	Timing []TimingFormat
+}
+
+// gapAfter は text の行の後に挿入する無音の長さを返します。
+func (o ConcatOptions) gapAfter(text string) time.Duration {
+	gap, matched := o.Gap, 0
+	for suffix, d := range o.PunctuationGaps {
+		if len(suffix) > matched && strings.HasSuffix(text, suffix) {
+			gap, matched = d, len(suffix)
+		}
+	}
+	return gap
+}
+
+// AudioSegment は結合する音声ファイルです。
+type AudioSegment struct {
+	Path string
+	// Text は音声の台本の行です。境界ごとの無音の長さの判定と字幕に使います。
+	Text string
+	// Timeline は結合した音声に含める、この音声の中の字幕とモーラの位置です。
+	// nil の場合は Text を音声全体の字幕にします。
+	Timeline *Timeline
+}
+
+// Timeline は結合した音声の中での字幕とモーラの位置です。
+type Timeline struct {
+	Cues  []Cue
+	Moras []MoraTiming
+}
+
+// Save は path の音声ファイルと同じ名前で、opts.Subtitles の字幕と opts.Timing のタイミングのファイルを保存します。
+func (t *Timeline) Save(path string, opts ConcatOptions) error {
+	if _, err := SaveSubtitles(path, t.Cues, opts.Subtitles); err != nil {
+		return err
+	}
+	_, err := SaveTimings(path, t.Moras, opts.Timing)
+	return err
+}
+
+// ConcatSegmentsToFile は segments を連結して path に保存し、字幕とモーラの位置を返します。
+// 失敗した場合は書きかけの path を削除します。
+func ConcatSegmentsToFile(path string, segments []AudioSegment, opts ConcatOptions) (*Timeline, error) {
+	return writeOutput(path, opts, func(w io.WriteSeeker) (*Timeline, error) {
+		return ConcatSegments(w, segments, opts)
+	})
+}
+
+// writeOutput は path を作成して write で書き込み、字幕とタイミングのファイルも保存します。
+// 失敗した場合は書きかけの path を削除します。
+func writeOutput(path string, opts ConcatOptions, write func(w io.WriteSeeker) (*Timeline, error)) (*Timeline, error) {
+	// 出力ファイルを作成
+	outputFile, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating output file: %v", err)
+	}
+	timeline, err := write(outputFile)
+	if cerr := outputFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	if err := timeline.Save(path, opts); err != nil {
+		return nil, err
+	}
+	return timeline, nil
+}
+
+// ConcatSegments は segments を順に読み込み、opts に従って無音やクロスフェードを挟みながら連結して w に書き込みます。
+// 結合した音声の中での字幕とモーラの位置を返します。
+func ConcatSegments(w io.WriteSeeker, segments []AudioSegment, opts ConcatOptions) (*Timeline, error) {
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("結合する音声ファイルがありません")
+	}
+	c := NewConcatenator(w, opts)
+	for _, seg := range segments {
+		f, err := wav.ReadFile(seg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file %s: %w", seg.Path, err)
+		}
+		buf, err := c.decode(f)
+		if err == nil {
+			err = c.add(buf, seg.Text, seg.Timeline)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", seg.Path, err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		return nil, err
+	}
+	return c.Timeline(), nil
+}
+
+// ConcatResultsToFile は results の音声を台本の順に連結して path に保存し、字幕とモーラの位置を返します。
+// 失敗した行があった場合や ctx がキャンセルされた場合は path を残さず、Drain と同じエラーを返します。
+func ConcatResultsToFile(ctx context.Context, path string, results iter.Seq[LineResult], opts ConcatOptions) (*Timeline, error) {
+	return writeOutput(path, opts, func(w io.WriteSeeker) (*Timeline, error) {
+		return ConcatResults(ctx, w, results, opts)
+	})
+}
+
+// ConcatResults は results の音声を受け取った順に連結して w に書き込み、字幕とモーラの位置を返します。
+// 行ごとの音声をファイルに保存せず、音声合成と並行して書き出します。
+func ConcatResults(ctx context.Context, w io.WriteSeeker, results iter.Seq[LineResult], opts ConcatOptions) (*Timeline, error) {
+	c := NewConcatenator(w, opts)
+	err := Drain(ctx, results, func(r LineResult) error {
+		f, err := wav.Decode(bytes.NewReader(r.Audio))
+		if err != nil {
+			return &LineError{Index: r.Index, Line: r.Line, Err: fmt.Errorf("error decoding WAV: %w", err)}
+		}
+		buf, err := c.decode(f)
+		if err == nil {
+			line := &Timeline{}
+			if r.Query != nil {
+				line.Moras = r.Query.MoraTimings(r.Line.Number)
+			}
+			err = c.add(buf, r.Line.Text, line)
+		}
+		if err != nil {
+			return &LineError{Index: r.Index, Line: r.Line, Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Close(); err != nil {
+		return nil, err
+	}
+	return c.Timeline(), nil
+}
+
+// Concatenator は音声を1つずつ受け取り、opts に従って無音やクロスフェードを挟みながら連結して書き込みます。
+// 正規化を行わない場合は受け取った音声をすぐに書き出すため、全体をメモリ上に保持しません。
+type Concatenator struct {
+	w    io.WriteSeeker
+	opts ConcatOptions
+
+	out    *wav.Writer
+	joiner *wav.Joiner
+	// whole は正規化のために結合した音声全体を保持するバッファです
+	whole *wav.Buffer
+	// n は追加した音声の数、prev は直前の音声の台本の行です
+	n        int
+	prev     string
+	timeline Timeline
+}
+
+// NewConcatenator は w に書き込む Concatenator を返します。WAVヘッダーは最初の音声を追加した時に書き込みます。
+func NewConcatenator(w io.WriteSeeker, opts ConcatOptions) *Concatenator {
+	return &Concatenator{w: w, opts: opts}
+}
+
+// start は出力の形式を決めてWAVヘッダーを書き込みます。
+func (c *Concatenator) start(format wav.Format) error {
+	if c.opts.Format != nil {
+		format = *c.opts.Format
+	}
+	out, err := wav.NewWriter(c.w, format)
+	if err != nil {
+		return fmt.Errorf("error writing WAV header: %w", err)
+	}
+	c.out = out
+	var dst wav.SampleWriter = out
+	if c.opts.Normalize.Mode != wav.NormalizeOff {
+		c.whole = &wav.Buffer{SampleRate: int(format.SampleRate), Channels: int(format.Channels)}
+		dst = c.whole
+	}
+	c.joiner = wav.NewJoiner(dst, int(format.SampleRate), int(format.Channels), wav.JoinOptions{
+		Crossfade: c.opts.Crossfade,
+		Curve:     c.opts.CrossfadeCurve,
+	})
+	return nil
+}
+
+// AddReader は r から読み込んだWAVファイルを text の行の音声として追加します。
+func (c *Concatenator) AddReader(r io.Reader, text string) error {
+	f, err := wav.Decode(r)
+	if err != nil {
+		return fmt.Errorf("error decoding WAV: %w", err)
+	}
+	return c.Add(f, text)
+}
+
+// Add は f を text の行の音声として追加します。
+// 形式が出力と異なる場合、opts.Convert が false であればエラーになります。
+func (c *Concatenator) Add(f *wav.File, text string) error {
+	buf, err := c.decode(f)
+	if err != nil {
+		return err
+	}
+	return c.add(buf, text, nil)
+}
+
+// decode は f の形式を確認してデコードします。
+func (c *Concatenator) decode(f *wav.File) (*wav.Buffer, error) {
+	if c.out == nil {
+		if err := c.start(f.Format); err != nil {
+			return nil, err
+		}
+	}
+	if f.Format != c.out.Format() && !c.opts.Convert {
+		return nil, &wav.FormatMismatchError{Index: c.n, Want: c.out.Format(), Got: f.Format}
+	}
+	buf, err := f.Buffer()
+	if err != nil {
+		return nil, fmt.Errorf("error decoding WAV: %w", err)
+	}
+	return buf, nil
+}
+
+// AddBuffer はデコード済みの音声 buf を text の行の音声として追加します。
+// サンプリングレートとチャンネル数は出力に合わせて変換します。
+// 最初の音声の場合、opts.Format が nil であれば buf と同じサンプリングレートとチャンネル数の16bit PCMで出力します。
+func (c *Concatenator) AddBuffer(buf *wav.Buffer, text string) error {
+	return c.add(buf, text, nil)
+}
+
+// add は buf を追加し、buf の中での字幕とモーラの位置 sub を記録します。
+// sub が nil か字幕を含まない場合は text を buf 全体の字幕にします。
+func (c *Concatenator) add(buf *wav.Buffer, text string, sub *Timeline) error {
+	if c.out == nil {
+		format := wav.Format{AudioFormat: wav.FormatPCM, Channels: uint16(buf.Channels), SampleRate: uint32(buf.SampleRate), BitsPerSample: 16}
+		if err := c.start(format); err != nil {
+			return err
+		}
+	}
+	buf = buf.Remix(int(c.out.Format().Channels)).Resample(int(c.out.Format().SampleRate))
+
+	var gap time.Duration
+	if c.n > 0 {
+		gap = c.opts.gapAfter(c.prev)
+	}
+	start, err := c.joiner.Add(buf, gap)
+	if err != nil {
+		return fmt.Errorf("error appending audio: %w", err)
+	}
+	if sub == nil {
+		sub = &Timeline{}
+	}
+	if sub.Cues == nil && strings.TrimSpace(text) != "" {
+		c.timeline.Cues = append(c.timeline.Cues, Cue{Start: start, End: start + buf.Duration(), Text: text})
+	}
+	c.timeline.Cues = append(c.timeline.Cues, shiftCues(sub.Cues, start)...)
+	c.timeline.Moras = append(c.timeline.Moras, shiftMoras(sub.Moras, start)...)
+	c.n++
+	c.prev = text
+	return nil
+}
+
+// Timeline は追加した音声の字幕とモーラの位置を返します。
+// クロスフェードで重なる区間は、次の字幕の開始で前の字幕を終えます。
+func (c *Concatenator) Timeline() *Timeline {
+	cues := slices.Clone(c.timeline.Cues)
+	for i := 1; i < len(cues); i++ {
+		cues[i-1].End = min(cues[i-1].End, cues[i].Start)
+	}
+	return &Timeline{Cues: cues, Moras: slices.Clone(c.timeline.Moras)}
+}
+
+// Close は残りの音声を書き出し、WAVヘッダーを更新します。w は閉じません。
+func (c *Concatenator) Close() error {
+	if c.out == nil {
+		return fmt.Errorf("結合する音声がありません")
+	}
+	if err := c.joiner.Close(); err != nil {
+		return fmt.Errorf("error appending audio: %w", err)
+	}
+	if c.whole != nil {
+		c.whole.Normalize(c.opts.Normalize)
+		if err := c.out.WriteSamples(c.whole.Samples); err != nil {
+			return fmt.Errorf("error writing normalized audio: %w", err)
+		}
+	}
+	// 出力ファイルのWAVヘッダーを更新
+	if err := c.out.Close(); err != nil {
+		return fmt.Errorf("error updating WAV header: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"voicevox/wav"
+)
+
+func TestConcatSegmentsFormatMismatch(t *testing.T) {
+	dir := t.TempDir()
+	engine := wav.Format{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 24000, BitsPerSample: 16}
+	first := filepath.Join(dir, "00000.wav")
+	// 100ミリ秒の無音
+	if err := wav.WriteFile(first, &wav.File{Format: engine, Data: make([]byte, 4800)}); err != nil {
+		t.Fatal(err)
+	}
+	// 44.1kHzで収録された無音ファイル
+	silent := filepath.Join("..", "asset", "silent_N.wav")
+	files := []string{first, silent}
+
+	out, err := os.Create(filepath.Join(dir, "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	_, err = ConcatSegments(out, segmentsOf(files), ConcatOptions{})
+	var merr *wav.FormatMismatchError
+	if !errors.As(err, &merr) {
+		t.Fatalf("expected FormatMismatchError, got %v", err)
+	}
+	if merr.Got.SampleRate != 44100 || !strings.Contains(err.Error(), silent) {
+		t.Errorf("エラーに形式の異なるファイルが示されていない: %v", err)
+	}
+
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConcatSegments(out, segmentsOf(files), ConcatOptions{Convert: true}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := wav.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := wav.ReadFile(silent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 100*time.Millisecond + s.Duration()
+	if got.Format != engine || (got.Duration()-want).Abs() > time.Millisecond {
+		t.Errorf("got %v %v, want %v %v", got.Format, got.Duration(), engine, want)
+	}
+}
+
+func TestConcatSegmentsGaps(t *testing.T) {
+	dir := t.TempDir()
+	// 100ミリ秒の一定値の音声を3つ用意する
+	var segments []AudioSegment
+	for i, text := range []string{"文です。", "続きです、", "最後です。"} {
+		b := &wav.Buffer{SampleRate: 24000, Channels: 1, Samples: make([]float64, 2400)}
+		for j := range b.Samples {
+			b.Samples[j] = 0.5
+		}
+		f, err := b.File(EngineFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%05d.wav", i))
+		if err := wav.WriteFile(path, f); err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, AudioSegment{Path: path, Text: text})
+	}
+
+	tests := []struct {
+		name string
+		opts ConcatOptions
+		want time.Duration
+	}{
+		{name: "無音なし", opts: ConcatOptions{}, want: 300 * time.Millisecond},
+		{name: "固定の無音", opts: ConcatOptions{Gap: 50 * time.Millisecond}, want: 400 * time.Millisecond},
+		{
+			name: "句読点ごとの無音",
+			opts: ConcatOptions{
+				Gap:             10 * time.Millisecond,
+				PunctuationGaps: map[string]time.Duration{"。": 200 * time.Millisecond, "、": 100 * time.Millisecond},
+			},
+			want: 600 * time.Millisecond,
+		},
+		{name: "クロスフェード", opts: ConcatOptions{Crossfade: 20 * time.Millisecond, CrossfadeCurve: wav.FadeLinear}, want: 260 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "out.wav")
+			if _, err := ConcatSegmentsToFile(path, segments, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			f, err := wav.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Duration() != tt.want {
+				t.Errorf("duration = %v, want %v", f.Duration(), tt.want)
+			}
+			if tt.opts.Crossfade > 0 {
+				// 一定値同士の線形クロスフェードは継ぎ目でも値が変わらない
+				b, _ := f.Buffer()
+				for i, v := range b.Samples {
+					if math.Abs(v-0.5) > 1e-3 {
+						t.Fatalf("sample %d = %v", i, v)
+					}
+				}
+			}
+		})
+	}
+}
+
+func segmentsOf(files []string) []AudioSegment {
+	segments := make([]AudioSegment, len(files))
+	for i, f := range files {
+		segments[i] = AudioSegment{Path: f}
+	}
+	return segments
+}
+
+func TestConcatSegmentsNormalize(t *testing.T) {
+	// 音量の異なる2つの行を結合し、全体を目標のラウドネスに揃える
+	dir := t.TempDir()
+	var segments []AudioSegment
+	for i, amp := range []float64{0.05, 0.5} {
+		buf := &wav.Buffer{SampleRate: 24000, Channels: 1}
+		for j := 0; j < 24000*2; j++ {
+			buf.Samples = append(buf.Samples, amp*math.Sin(2*math.Pi*1000*float64(j)/24000))
+		}
+		f, err := buf.File(EngineFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := fmt.Sprintf("%s/%05d.wav", dir, i)
+		if err := wav.WriteFile(path, f); err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, AudioSegment{Path: path})
+	}
+
+	out := dir + "/out.wav"
+	opts := ConcatOptions{Normalize: wav.DefaultNormalizeOptions(wav.NormalizeLoudness)}
+	if _, err := ConcatSegmentsToFile(out, segments, opts); err != nil {
+		t.Fatal(err)
+	}
+	f, err := wav.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := f.Buffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Duration() != 4*time.Second {
+		t.Errorf("duration = %v", buf.Duration())
+	}
+	if got := buf.Loudness(); math.Abs(got-wav.DefaultLoudnessTarget) > 0.1 {
+		t.Errorf("loudness = %.2f LUFS, want %v", got, wav.DefaultLoudnessTarget)
+	}
+}
+
+func TestConcatResults(t *testing.T) {
+	line := func(s string) ScriptLine { return ScriptLine{Number: 1, Text: s} }
+	audio := func(d time.Duration) []byte {
+		var buf bytes.Buffer
+		if err := wav.Encode(&buf, wav.Silence(EngineFormat, d)); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	seq := func(results ...LineResult) iter.Seq[LineResult] {
+		return slices.Values(results)
+	}
+	opts := ConcatOptions{PunctuationGaps: map[string]time.Duration{"。": 200 * time.Millisecond}}
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "out.wav")
+	results := seq(
+		LineResult{Index: 0, Line: line("一。"), Audio: audio(100 * time.Millisecond)},
+		LineResult{Index: 1, Line: line("二"), Audio: audio(50 * time.Millisecond)},
+		LineResult{Index: 2, Line: line("三。"), Audio: audio(100 * time.Millisecond)},
+	)
+	opts.Subtitles = []SubtitleFormat{SubtitleSRT}
+	timeline, err := ConcatResultsToFile(context.Background(), path, results, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCues := []Cue{
+		{Start: 0, End: 100 * time.Millisecond, Text: "一。"},
+		{Start: 300 * time.Millisecond, End: 350 * time.Millisecond, Text: "二"},
+		{Start: 350 * time.Millisecond, End: 450 * time.Millisecond, Text: "三。"},
+	}
+	if !slices.Equal(timeline.Cues, wantCues) {
+		t.Errorf("cues = %v, want %v", timeline.Cues, wantCues)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.srt")); err != nil {
+		t.Errorf("字幕ファイルが保存されていない: %v", err)
+	}
+	f, err := wav.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 450 * time.Millisecond; f.Format != EngineFormat || f.Duration() != want {
+		t.Errorf("got %v %v, want %v", f.Format, f.Duration(), want)
+	}
+
+	// 失敗した行があった場合は出力を残さない
+	path = filepath.Join(dir, "failed.wav")
+	results = seq(
+		LineResult{Index: 0, Line: line("一。"), Audio: audio(100 * time.Millisecond)},
+		LineResult{Index: 1, Line: line("二。"), Err: errors.New("failed")},
+	)
+	_, err = ConcatResultsToFile(context.Background(), path, results, opts)
+	var serr *SynthesisError
+	if !errors.As(err, &serr) || len(serr.Failed) != 1 {
+		t.Errorf("err = %v, want *SynthesisError", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("失敗した出力が残っている: %v", err)
+	}
+}
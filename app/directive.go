@@ -0,0 +1,297 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ScriptLine は台本の1行(1回の音声合成の単位)です。
+type ScriptLine struct {
+	// Number は台本内の行番号(1始まり)です。
+	Number int
+	// Text は行頭タグとルビの記法を取り除いた、字幕などに表示する本文です。空文字列は無音行を表します。
+	Text string
+	// Reading はルビを振った語を読み方に置き換えた本文で、音声合成に使います。ルビのない行では空文字列です。
+	Reading string
+	// Break は無音行の種類です。
+	Break BreakKind
+	// Prosody は行頭タグで指定された韻律の調整です。
+	Prosody Prosody
+	// Speaker は行頭の話者ラベル(`ずんだもん: ...` の「ずんだもん」)です。
+	// ラベルのない行では空文字列になります。
+	Speaker string
+	// SpeakerID は音声合成に使うスタイルIDです。SpeakerConfig.Assign で設定されます。
+	SpeakerID int
+
+	// label は本文から取り除いた話者ラベルの原文(区切り文字を含む)です。
+	label string
+}
+
+// Prosody は行頭タグ `[speed=1.2 pitch=0.05 pause=800ms]` で指定される韻律の調整です。
+// nil のフィールドはエンジンが返した値をそのまま使います。
+type Prosody struct {
+	Speed      *float64       // speed: 話速 (speedScale)
+	Pitch      *float64       // pitch: 音高 (pitchScale)
+	Intonation *float64       // intonation: 抑揚 (intonationScale)
+	Volume     *float64       // volume: 音量 (volumeScale)
+	Pre        *time.Duration // pre: 行頭の無音 (prePhonemeLength)
+	Pause      *time.Duration // pause: 行末の無音 (postPhonemeLength)
+}
+
+// Apply は指定された値でクエリを上書きします。
+func (p Prosody) Apply(q *AudioQuery) {
+	if p.Speed != nil {
+		q.SpeedScale = *p.Speed
+	}
+	if p.Pitch != nil {
+		q.PitchScale = *p.Pitch
+	}
+	if p.Intonation != nil {
+		q.IntonationScale = *p.Intonation
+	}
+	if p.Volume != nil {
+		q.VolumeScale = *p.Volume
+	}
+	if p.Pre != nil {
+		q.PrePhonemeLength = p.Pre.Seconds()
+	}
+	if p.Pause != nil {
+		q.PostPhonemeLength = p.Pause.Seconds()
+	}
+}
+
+// ScriptError は台本の解析エラーです。
+type ScriptError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%d行目: %s", e.Line, e.Msg)
+}
+
+// ReadScriptFile は台本ファイルを enc の文字コードで読み込み、各行を解析します。
+// enc が EncodingAuto の場合は内容から判定し、UTF-8 の BOM は取り除きます。
+func ReadScriptFile(path string, enc Encoding) ([]ScriptLine, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("台本ファイルを開く際にエラーが発生しました: %w", err)
+	}
+	text, err := DecodeText(content, enc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("台本ファイルの読み込み中にエラーが発生しました: %w", err)
+	}
+	script, err := ParseScript(lines)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return script, nil
+}
+
+// ParseScript は台本の各行を解析します。空行は段落の区切り、句読点のみの行は文の区切りの無音行になります。
+// 解析できない行が複数ある場合は、全ての行のエラーをまとめて返します。
+func ParseScript(lines []string) ([]ScriptLine, error) {
+	segments := make([]Segment, len(lines))
+	for i, line := range lines {
+		segments[i] = Segment{Text: line}
+	}
+	return ParseSegments(segments)
+}
+
+// ParseSegments は CreateSegments が生成した各行を解析します。
+// 無音行の種類は Segment の値を引き継ぎます。
+func ParseSegments(segments []Segment) ([]ScriptLine, error) {
+	script := make([]ScriptLine, 0, len(segments))
+	var errs []error
+	for i, seg := range segments {
+		l, err := ParseScriptLine(i+1, seg.Text)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if l.Text == "。" || l.Text == "、" {
+			l.Text, l.Break = "", BreakSentence
+		}
+		if l.Text == "" && l.Break == BreakNone {
+			l.Break = seg.Break
+			if l.Break == BreakNone {
+				l.Break = BreakParagraph
+			}
+		}
+		script = append(script, l)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return script, nil
+}
+
+// ParseScriptLine は台本の1行を解析し、話者ラベルと行頭タグを取り除いた本文と韻律の指定を返します。
+// 話者ラベルと行頭タグはどちらが先に書かれていても構いません。
+func ParseScriptLine(number int, line string) (ScriptLine, error) {
+	line = strings.TrimSpace(line)
+	label, speaker, rest := splitSpeakerLabel(line)
+	tag, text := splitDirectiveTag(rest)
+	if label == "" && tag != "" {
+		label, speaker, text = splitSpeakerLabel(text)
+	}
+	l := ScriptLine{Number: number, Speaker: speaker, label: label}
+	l.Text, l.Reading = ParseRuby(text)
+	if tag == "" {
+		return l, nil
+	}
+	p, err := parseDirectives(tag)
+	if err != nil {
+		return ScriptLine{}, &ScriptError{Line: number, Msg: err.Error()}
+	}
+	l.Prosody = p
+	return l, nil
+}
+
+// maxSpeakerLabelLength は話者ラベルとみなす名前の最大文字数です。
+const maxSpeakerLabelLength = 16
+
+// splitSpeakerLabel は行頭の `名前:` または `名前：` を取り出します。label は後ろの空白を含みます。
+// 名前に空白や句読点を含む場合はラベルとみなしません。
+// ここで取り出したのはラベルの候補で、話者設定にない名前は SpeakerConfig.Assign で本文に戻します。
+func splitSpeakerLabel(line string) (label, speaker, text string) {
+	i := strings.IndexAny(line, ":：")
+	if i <= 0 {
+		return "", "", line
+	}
+	name := line[:i]
+	if utf8.RuneCountInString(name) > maxSpeakerLabelLength || strings.ContainsAny(name, " \t　。、「」[]") {
+		return "", "", line
+	}
+	// 時刻(10:30)やURL(https://)はラベルとみなさない
+	if strings.Trim(name, "0123456789") == "" || strings.HasPrefix(line[i:], "://") {
+		return "", "", line
+	}
+	_, size := utf8.DecodeRuneInString(line[i:])
+	text = strings.TrimSpace(line[i+size:])
+	return line[:len(line)-len(text)], name, text
+}
+
+// splitDirectiveTag は行頭の `[...]` タグと残りの本文を分割します。
+// 行頭にタグがない場合は tag が空文字列になります。
+// `[1]` や `[注意]` のように key=value の形の指示を含まない括弧は本文の一部とみなします。
+func splitDirectiveTag(line string) (tag, text string) {
+	if !strings.HasPrefix(line, "[") {
+		return "", line
+	}
+	end := strings.Index(line, "]")
+	if end < 0 || !strings.Contains(line[:end], "=") {
+		return "", line
+	}
+	return line[:end+1], strings.TrimSpace(line[end+1:])
+}
+
+// parseDirectives は `[speed=1.2 pitch=0.05]` 形式のタグを解析します。
+func parseDirectives(tag string) (Prosody, error) {
+	var p Prosody
+	body := strings.TrimSuffix(strings.TrimPrefix(tag, "["), "]")
+	fields := strings.FieldsFunc(body, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(fields) == 0 {
+		return p, fmt.Errorf("空のタグ %s", tag)
+	}
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || value == "" {
+			return p, fmt.Errorf("指示 %q は key=value の形式で指定してください", f)
+		}
+		switch key {
+		case "speed":
+			v, err := parseScale(key, value, false)
+			if err != nil {
+				return p, err
+			}
+			p.Speed = &v
+		case "pitch":
+			v, err := parseFloat(key, value)
+			if err != nil {
+				return p, err
+			}
+			p.Pitch = &v
+		case "intonation":
+			v, err := parseScale(key, value, true)
+			if err != nil {
+				return p, err
+			}
+			p.Intonation = &v
+		case "volume":
+			v, err := parseScale(key, value, true)
+			if err != nil {
+				return p, err
+			}
+			p.Volume = &v
+		case "pre":
+			d, err := parseDuration(key, value)
+			if err != nil {
+				return p, err
+			}
+			p.Pre = &d
+		case "pause":
+			d, err := parseDuration(key, value)
+			if err != nil {
+				return p, err
+			}
+			p.Pause = &d
+		default:
+			return p, fmt.Errorf("未知の指示 %q です (speed, pitch, intonation, volume, pre, pause のいずれかを指定してください)", key)
+		}
+	}
+	return p, nil
+}
+
+func parseFloat(key, value string) (float64, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s の値 %q が数値ではありません", key, value)
+	}
+	return v, nil
+}
+
+// parseScale は倍率を解析します。allowZero が false の場合は0も不正な値とします。
+func parseScale(key, value string, allowZero bool) (float64, error) {
+	v, err := parseFloat(key, value)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || (v == 0 && !allowZero) {
+		return 0, fmt.Errorf("%s の値 %v は範囲外です", key, v)
+	}
+	return v, nil
+}
+
+// parseDuration は "800ms" や "1.5s" 形式の長さを解析します。単位を省略した場合は秒として扱います。
+func parseDuration(key, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		sec, ferr := strconv.ParseFloat(value, 64)
+		if ferr != nil {
+			return 0, fmt.Errorf("%s の値 %q が長さではありません (例: 800ms)", key, value)
+		}
+		d = time.Duration(sec * float64(time.Second))
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s の値 %v は0以上にしてください", key, d)
+	}
+	return d, nil
+}
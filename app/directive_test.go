@@ -0,0 +1,131 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScript(t *testing.T) {
+	lines := []string{
+		"[speed=1.2 pitch=0.05 pause=800ms] 大事な文です。",
+		"普通の文です。",
+		"",
+		"[volume=0.8,pre=0.5] 小さめの声で、",
+	}
+	script, err := ParseScript(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(script) != len(lines) {
+		t.Fatalf("len = %d", len(script))
+	}
+
+	first := script[0]
+	if first.Number != 1 || first.Text != "大事な文です。" {
+		t.Errorf("unexpected line: %+v", first)
+	}
+	q := AudioQuery{SpeedScale: 1, PitchScale: 0, VolumeScale: 1, PostPhonemeLength: 0.1}
+	first.Prosody.Apply(&q)
+	if q.SpeedScale != 1.2 || q.PitchScale != 0.05 || q.PostPhonemeLength != 0.8 || q.VolumeScale != 1 {
+		t.Errorf("unexpected query: %+v", q)
+	}
+
+	if script[1].Text != "普通の文です。" || script[1].Prosody != (Prosody{}) {
+		t.Errorf("タグなしの行: %+v", script[1])
+	}
+	if script[2].Text != "" {
+		t.Errorf("無音行: %+v", script[2])
+	}
+	last := script[3]
+	if last.Text != "小さめの声で、" || *last.Prosody.Volume != 0.8 || *last.Prosody.Pre != 500*time.Millisecond {
+		t.Errorf("unexpected line: %+v", last)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	lines := []string{
+		"正しい行です。",
+		"[speed=1.2 tempo=3] 未知の指示。",
+		"[pause=long] 長さではない。",
+		"[speed=0] 範囲外。",
+	}
+	_, err := ParseScript(lines)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	var serr *ScriptError
+	if !errors.As(err, &serr) || serr.Line != 2 {
+		t.Errorf("最初のエラーは2行目のはず: %v", err)
+	}
+	for _, want := range []string{"2行目", "tempo", "3行目", "4行目"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("エラーに %q が含まれていない: %v", want, err)
+		}
+	}
+}
+
+func TestParseScriptBracketText(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"番号", "[1] 次の問題を解け。", "[1] 次の問題を解け。"},
+		{"見出し", "[注意] 解答は一つです。", "[注意] 解答は一つです。"},
+		{"空の括弧", "[] 何もない。", "[] 何もない。"},
+		{"指示の後の括弧", "[speed=1.2] [1] 速く読む。", "[1] 速く読む。"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := ParseScript([]string{tt.line})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if script[0].Text != tt.want {
+				t.Errorf("Text = %q, want %q", script[0].Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateKeepsDirectiveTag(t *testing.T) {
+	lineNum := 20
+	tmpFile := createTempFile(t, "[speed=0.9] ここは大事です。ゆっくり読みます。\n普通の文。")
+	got, err := Create(tmpFile, &lineNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"[speed=0.9] ここは大事です。",
+		"[speed=0.9] ゆっくり読みます。",
+		"普通の文。",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCreateBracketText(t *testing.T) {
+	lineNum := 40
+	tmpFile := createTempFile(t, "[1] 次の問題を解け。\n[注意] 解答は一つです。")
+	got, err := Create(tmpFile, &lineNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := ParseScript(got)
+	if err != nil {
+		t.Fatalf("括弧で始まる本文がエラーになった: %v", err)
+	}
+	var texts []string
+	for _, l := range script {
+		if l.Text != "" {
+			texts = append(texts, l.Text)
+		}
+	}
+	want := []string{"[1] 次の問題を解け。", "[注意] 解答は一つです。"}
+	if strings.Join(texts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", texts, want)
+	}
+}
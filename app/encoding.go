@@ -0,0 +1,249 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// Encoding は入力ファイルの文字コードです。
+type Encoding string
+
+const (
+	// EncodingAuto は内容から文字コードを判定します。
+	EncodingAuto     Encoding = ""
+	EncodingUTF8     Encoding = "utf-8"
+	EncodingShiftJIS Encoding = "shift_jis"
+	EncodingEUCJP    Encoding = "euc-jp"
+)
+
+// ParseEncoding は文字コードの名前(auto, utf-8, shift_jis, euc-jp と、sjis, cp932 などの別名)を解析します。
+func ParseEncoding(s string) (Encoding, error) {
+	switch strings.ToLower(strings.ReplaceAll(s, "_", "-")) {
+	case "", "auto":
+		return EncodingAuto, nil
+	case "utf-8", "utf8":
+		return EncodingUTF8, nil
+	case "shift-jis", "sjis", "cp932", "windows-31j":
+		return EncodingShiftJIS, nil
+	case "euc-jp", "eucjp":
+		return EncodingEUCJP, nil
+	}
+	return "", fmt.Errorf("不明な文字コードです: %q (auto, utf-8, shift_jis, euc-jp のいずれかを指定してください)", s)
+}
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingAuto:
+		return "auto"
+	case EncodingUTF8:
+		return "UTF-8"
+	case EncodingShiftJIS:
+		return "Shift_JIS"
+	case EncodingEUCJP:
+		return "EUC-JP"
+	}
+	return string(e)
+}
+
+// EncodingError は入力が指定された文字コードとして正しくない場合のエラーです。
+type EncodingError struct {
+	Encoding Encoding
+	// Offset は最初の不正なバイトの位置(0から)です。
+	Offset int
+}
+
+func (e *EncodingError) Error() string {
+	if e.Encoding == EncodingAuto {
+		return "文字コードを判定できません(UTF-8, Shift_JIS, EUC-JP のいずれでもありません)。文字コードを指定してください"
+	}
+	return fmt.Sprintf("%s として正しくないバイトがあります(%dバイト目)。文字コードを指定してください", e.Encoding, e.Offset+1)
+}
+
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// DecodeText は enc の文字コードの data を文字列にします。enc が EncodingAuto の場合は DetectEncoding で判定します。
+// UTF-8 の BOM は取り除きます。data が enc として正しくない場合や、文字が割り当てられていない符号を含む場合は
+// *EncodingError を返します。
+func DecodeText(data []byte, enc Encoding) (string, error) {
+	if enc == EncodingAuto {
+		var err error
+		if enc, err = DetectEncoding(data); err != nil {
+			return "", err
+		}
+	}
+	if n := invalidOffset(data, enc); n >= 0 {
+		return "", &EncodingError{Encoding: enc, Offset: n}
+	}
+	dec := decoder(enc)
+	if dec == nil {
+		return string(bytes.TrimPrefix(data, utf8BOM)), nil
+	}
+	out, err := dec.Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	// バイトの並びは正しくても文字が割り当てられていない符号は U+FFFD になる
+	if bytes.ContainsRune(out, utf8.RuneError) {
+		if n := unmappedOffset(data, enc); n >= 0 {
+			return "", &EncodingError{Encoding: enc, Offset: n}
+		}
+	}
+	return string(out), nil
+}
+
+// decoder は enc の文字列を UTF-8 にするデコーダーを返します。UTF-8 の場合は nil です。
+func decoder(enc Encoding) *encoding.Decoder {
+	switch enc {
+	case EncodingShiftJIS:
+		return japanese.ShiftJIS.NewDecoder()
+	case EncodingEUCJP:
+		return japanese.EUCJP.NewDecoder()
+	}
+	return nil
+}
+
+// unmappedOffset は enc として正しいバイトの並びの data のうち、文字が割り当てられていない最初の文字の位置を返します。
+// 全ての文字が割り当てられている場合は -1 です。
+func unmappedOffset(data []byte, enc Encoding) int {
+	dec := decoder(enc)
+	for i := 0; i < len(data); {
+		n := charSize(data[i:], enc)
+		if out, _ := dec.Bytes(data[i : i+n]); bytes.ContainsRune(out, utf8.RuneError) {
+			return i
+		}
+		i += n
+	}
+	return -1
+}
+
+// charSize は enc として正しいバイトの並びの data の、先頭の文字のバイト数を返します。
+func charSize(data []byte, enc Encoding) int {
+	b := data[0]
+	switch {
+	case b < 0x80:
+		return 1
+	case enc == EncodingShiftJIS && b >= 0xA1 && b <= 0xDF:
+		// 半角カタカナ
+		return 1
+	case enc == EncodingEUCJP && b == 0x8F:
+		return 3
+	}
+	return 2
+}
+
+// DetectEncoding は data の文字コードを判定します。
+// BOM があるか UTF-8 として正しければ UTF-8、そうでなければ Shift_JIS と EUC-JP のうち正しく読める方を返します。
+// 両方として読める場合は、かな・漢字として読める文字の多い方を選びます。
+func DetectEncoding(data []byte) (Encoding, error) {
+	if bytes.HasPrefix(data, utf8BOM) || utf8.Valid(data) {
+		return EncodingUTF8, nil
+	}
+	sjis := invalidOffset(data, EncodingShiftJIS) < 0
+	euc := invalidOffset(data, EncodingEUCJP) < 0
+	switch {
+	case sjis && euc:
+		s, _ := japanese.ShiftJIS.NewDecoder().Bytes(data)
+		e, _ := japanese.EUCJP.NewDecoder().Bytes(data)
+		if japaneseRunes(e) > japaneseRunes(s) {
+			return EncodingEUCJP, nil
+		}
+		return EncodingShiftJIS, nil
+	case sjis:
+		return EncodingShiftJIS, nil
+	case euc:
+		return EncodingEUCJP, nil
+	}
+	return EncodingAuto, &EncodingError{}
+}
+
+// japaneseRunes は全角のかな・漢字と句読点の数を返します。
+func japaneseRunes(s []byte) int {
+	n := 0
+	for _, r := range string(s) {
+		if unicode.In(r, unicode.Hiragana, unicode.Han) || (r >= 'ァ' && r <= 'ヶ') || r == '。' || r == '、' || r == 'ー' {
+			n++
+		}
+	}
+	return n
+}
+
+// invalidOffset は data が enc として正しくない最初のバイトの位置を返します。正しい場合は -1 です。
+func invalidOffset(data []byte, enc Encoding) int {
+	switch enc {
+	case EncodingShiftJIS:
+		return invalidShiftJIS(data)
+	case EncodingEUCJP:
+		return invalidEUCJP(data)
+	}
+	start := 0
+	if bytes.HasPrefix(data, utf8BOM) {
+		start = len(utf8BOM)
+	}
+	for i := start; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+// invalidShiftJIS は data が Shift_JIS(Windows-31J)として正しくない最初のバイトの位置を返します。
+func invalidShiftJIS(data []byte) int {
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b < 0x80, b >= 0xA1 && b <= 0xDF:
+			// ASCII と半角カタカナ
+		case b >= 0x81 && b <= 0x9F, b >= 0xE0 && b <= 0xFC:
+			if i+1 >= len(data) {
+				return i
+			}
+			if t := data[i+1]; t < 0x40 || t == 0x7F || t > 0xFC {
+				return i
+			}
+			i++
+		default:
+			return i
+		}
+	}
+	return -1
+}
+
+// invalidEUCJP は data が EUC-JP として正しくない最初のバイトの位置を返します。
+func invalidEUCJP(data []byte) int {
+	isTrail := func(i int) bool { return i < len(data) && data[i] >= 0xA1 && data[i] <= 0xFE }
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b < 0x80:
+		case b == 0x8E:
+			// 半角カタカナ
+			if i+1 >= len(data) || data[i+1] < 0xA1 || data[i+1] > 0xDF {
+				return i
+			}
+			i++
+		case b == 0x8F:
+			// JIS X 0212 の補助漢字
+			if !isTrail(i+1) || !isTrail(i+2) {
+				return i
+			}
+			i += 2
+		case b >= 0xA1 && b <= 0xFE:
+			if !isTrail(i + 1) {
+				return i
+			}
+			i++
+		default:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+const encodingSample = "ずんだもんなのだ。ﾊﾝｶｸと漢字、ＦＰ3級。"
+
+func encode(t *testing.T, enc Encoding, s string) []byte {
+	t.Helper()
+	var out string
+	var err error
+	switch enc {
+	case EncodingShiftJIS:
+		out, err = japanese.ShiftJIS.NewEncoder().String(s)
+	case EncodingEUCJP:
+		out, err = japanese.EUCJP.NewEncoder().String(s)
+	default:
+		out = s
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(out)
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		enc  Encoding
+		want Encoding
+	}{
+		{"UTF-8", encode(t, EncodingUTF8, encodingSample), EncodingAuto, EncodingUTF8},
+		{"UTF-8 BOM", append([]byte("\xEF\xBB\xBF"), encodingSample...), EncodingAuto, EncodingUTF8},
+		{"Shift_JIS", encode(t, EncodingShiftJIS, encodingSample), EncodingAuto, EncodingShiftJIS},
+		{"EUC-JP", encode(t, EncodingEUCJP, encodingSample), EncodingAuto, EncodingEUCJP},
+		{"EUC-JP のかなだけ", encode(t, EncodingEUCJP, "あいうえお"), EncodingAuto, EncodingEUCJP},
+		{"指定", encode(t, EncodingEUCJP, encodingSample), EncodingEUCJP, EncodingEUCJP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.enc == EncodingAuto {
+				got, err := DetectEncoding(tt.data)
+				if err != nil || got != tt.want {
+					t.Errorf("DetectEncoding() = %v, %v, want %v", got, err, tt.want)
+				}
+			}
+			text, err := DecodeText(tt.data, tt.enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := encodingSample
+			if tt.name == "EUC-JP のかなだけ" {
+				want = "あいうえお"
+			}
+			if text != want {
+				t.Errorf("DecodeText() = %q, want %q", text, want)
+			}
+		})
+	}
+}
+
+func TestDecodeTextInvalid(t *testing.T) {
+	sjis := encode(t, EncodingShiftJIS, "abcずんだ")
+	euc := encode(t, EncodingEUCJP, "abcずんだ")
+	tests := []struct {
+		name       string
+		data       []byte
+		enc        Encoding
+		wantEnc    Encoding
+		wantOffset int
+	}{
+		{"UTF-8 として読めない", sjis, EncodingUTF8, EncodingUTF8, 3},
+		{"EUC-JP として読めない", sjis, EncodingEUCJP, EncodingEUCJP, 3},
+		{"Shift_JIS の途中で終わる", sjis[:len(sjis)-1], EncodingShiftJIS, EncodingShiftJIS, len(sjis) - 2},
+		{"判定できない", []byte("abc\xFF\xFE"), EncodingAuto, EncodingAuto, 0},
+		// バイトの並びは正しいが文字が割り当てられていない
+		{"Shift_JIS の割り当てのない文字", slices.Concat(sjis, []byte("\x85\x40xyz")), EncodingShiftJIS, EncodingShiftJIS, len(sjis)},
+		{"EUC-JP の割り当てのない文字", slices.Concat(euc, []byte("\xA9\xA1")), EncodingEUCJP, EncodingEUCJP, len(euc)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeText(tt.data, tt.enc)
+			var encErr *EncodingError
+			if !errors.As(err, &encErr) {
+				t.Fatalf("DecodeText() error = %v, want *EncodingError", err)
+			}
+			if encErr.Encoding != tt.wantEnc || encErr.Offset != tt.wantOffset {
+				t.Errorf("error = %+v, want %v at %d", encErr, tt.wantEnc, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for s, want := range map[string]Encoding{"": EncodingAuto, "auto": EncodingAuto, "UTF8": EncodingUTF8, "sjis": EncodingShiftJIS, "Shift_JIS": EncodingShiftJIS, "cp932": EncodingShiftJIS, "euc-jp": EncodingEUCJP} {
+		if got, err := ParseEncoding(s); err != nil || got != want {
+			t.Errorf("ParseEncoding(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseEncoding("utf-16"); err == nil {
+		t.Error("不明な文字コードはエラーになるはず")
+	}
+}
+
+func TestReadInputEncoding(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "euc.txt")
+	if err := os.WriteFile(path, encode(t, EncodingEUCJP, "最初の文です。次の文です。"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := 20
+	segments, err := CreateSegments(path, &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Segment{{Text: "最初の文です。"}, {Text: "次の文です。"}}; !reflect.DeepEqual(segments, want) {
+		t.Errorf("CreateSegments() = %q, want %q", segments, want)
+	}
+
+	lines, err := ExtractLines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"最初の文です", "次の文です"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("ExtractLines() = %q, want %q", lines, want)
+	}
+
+	// 指定した文字コードとして読めない場合はエラー
+	path = filepath.Join(dir, "utf8.txt")
+	if err := os.WriteFile(path, []byte("最初の文です。"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultInputOptions()
+	opts.Encoding = EncodingEUCJP
+	var encErr *EncodingError
+	if _, err := CreateSegmentsWith(path, &l, opts); !errors.As(err, &encErr) {
+		t.Errorf("EUC-JP として読めないファイルのエラー = %v", err)
+	}
+}
+
+func TestReadScriptFileEncoding(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Shift_JIS", encode(t, EncodingShiftJIS, "[speed=1.2] ずんだもん: 最初の文です。\r\n次の文です。\r\n")},
+		{"UTF-8 BOM", append([]byte("\xEF\xBB\xBF"), "[speed=1.2] ずんだもん: 最初の文です。\n次の文です。\n"...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".txt")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			script, err := ReadScriptFile(path, EncodingAuto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(script) != 2 {
+				t.Fatalf("len = %d", len(script))
+			}
+			first := script[0]
+			if first.Text != "最初の文です。" || first.Speaker != "ずんだもん" || first.Prosody.Speed == nil || *first.Prosody.Speed != 1.2 {
+				t.Errorf("1行目のタグと話者ラベル: %+v", first)
+			}
+			if script[1].Text != "次の文です。" {
+				t.Errorf("2行目: %+v", script[1])
+			}
+		})
+	}
+
+	// 指定した文字コードとして読めない場合はエラー
+	path := filepath.Join(dir, "utf8.txt")
+	if err := os.WriteFile(path, []byte("最初の文です。"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var encErr *EncodingError
+	if _, err := ReadScriptFile(path, EncodingEUCJP); !errors.As(err, &encErr) {
+		t.Errorf("EUC-JP として読めない台本ファイルのエラー = %v", err)
+	}
+}
@@ -30,16 +30,25 @@ func CreateDirAndRemoveFiles(path string) error {
 	return nil
 }
 
+// ExtractLines はテキストファイルを「。」で区切った文の一覧を返します。文字コードは内容から判定します。
 func ExtractLines(filepath string) ([]string, error) {
-	file, err := os.Open(filepath)
+	return ExtractLinesEncoding(filepath, EncodingAuto)
+}
+
+// ExtractLinesEncoding は ExtractLines と同様ですが、ファイルを enc の文字コードとして読み込みます。
+func ExtractLinesEncoding(filepath string, enc Encoding) ([]string, error) {
+	data, err := os.ReadFile(filepath)
 	if err != nil {
 		fmt.Println("ファイルを開く際にエラーが発生しました: ", err)
 		return nil, err
 	}
-	defer file.Close()
+	text, err := DecodeText(data, enc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath, err)
+	}
 
 	var lines []string
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(strings.NewReader(text))
 	for scanner.Scan() {
 		line := scanner.Text()
 		parts := strings.Split(line, "。")
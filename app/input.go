@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+	"os"
+)
+
+// InputFormat は入力ファイルの形式です。
+type InputFormat string
+
+const (
+	// FormatAuto は拡張子から形式を判定します(.md, .markdown は Markdown、それ以外はテキスト)。
+	FormatAuto InputFormat = ""
+	// FormatText はそのまま台本にするテキストです。
+	FormatText InputFormat = "text"
+	// FormatMarkdown は Markdown です。MarkdownText で記法を取り除きます。
+	FormatMarkdown InputFormat = "markdown"
+	// FormatAozora は青空文庫形式のテキストです。AozoraText で書誌情報と注記を取り除きます。
+	FormatAozora InputFormat = "aozora"
+)
+
+// ParseInputFormat は形式の名前(auto, text, markdown, aozora)を解析します。
+func ParseInputFormat(s string) (InputFormat, error) {
+	switch f := InputFormat(s); f {
+	case "auto":
+		return FormatAuto, nil
+	case FormatAuto, FormatText, FormatMarkdown, FormatAozora:
+		return f, nil
+	}
+	return "", fmt.Errorf("不明な入力の形式です: %q (auto, text, markdown, aozora のいずれかを指定してください)", s)
+}
+
+// InputOptions は入力ファイルを台本にする前の変換の設定です。
+type InputOptions struct {
+	Format InputFormat
+	// Encoding は入力ファイルの文字コードです。EncodingAuto の場合は内容から判定します。
+	Encoding Encoding
+	Markdown MarkdownOptions
+	Aozora   AozoraOptions
+}
+
+// DefaultInputOptions は既定の変換の設定を返します。
+func DefaultInputOptions() InputOptions {
+	return InputOptions{Markdown: DefaultMarkdownOptions(), Aozora: DefaultAozoraOptions()}
+}
+
+// format は path の形式を返します。
+func (o InputOptions) format(path string) InputFormat {
+	if o.Format != FormatAuto {
+		return o.Format
+	}
+	if IsMarkdown(path) {
+		return FormatMarkdown
+	}
+	return FormatText
+}
+
+// ReadInput は入力ファイルを読み込んで UTF-8 にし、形式に応じて SegmentText で台本にできるテキストに変換します。
+func ReadInput(path string, opts InputOptions) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file content: %w", err)
+	}
+	text, err := DecodeText(content, opts.Encoding)
+	if err != nil {
+		return "", err
+	}
+	switch opts.format(path) {
+	case FormatMarkdown:
+		return MarkdownText(text, opts.Markdown), nil
+	case FormatAozora:
+		return AozoraText(text, opts.Aozora), nil
+	}
+	return text, nil
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultHeadingPause は見出しの後に挿入する無音の既定の長さです。段落の区切りより長くしておく。
+const DefaultHeadingPause = 1200 * time.Millisecond
+
+// MarkdownOptions は Markdown を読み上げ用のテキストに変換する設定です。
+type MarkdownOptions struct {
+	// HeadingPause は見出しの後に挿入する無音の長さです。0 の場合は無音を挿入しません。
+	HeadingPause time.Duration
+	// CodeBlock はコードブロックの代わりに読み上げる文です。空の場合はコードブロックを読み飛ばします。
+	CodeBlock string
+}
+
+// DefaultMarkdownOptions は既定の変換の設定を返します。
+func DefaultMarkdownOptions() MarkdownOptions {
+	return MarkdownOptions{HeadingPause: DefaultHeadingPause}
+}
+
+// IsMarkdown は path が Markdown ファイル(.md, .markdown)かどうかを返します。
+func IsMarkdown(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+var (
+	mdFence      = regexp.MustCompile("^\\s{0,3}(`{3,}|~{3,})")
+	mdHeading    = regexp.MustCompile(`^\s{0,3}#{1,6}(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	mdSetext     = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdRule       = regexp.MustCompile(`^\s{0,3}([-*_])(?:\s*[-*_]){2,}\s*$`)
+	mdListItem   = regexp.MustCompile(`^\s*(?:[-*+]|\d{1,9}[.)])\s+(?:\[[ xX]\]\s+)?`)
+	mdQuote      = regexp.MustCompile(`^\s{0,3}(?:>\s?)+`)
+	mdLinkDef    = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*\S+`)
+	mdTableRule  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdComment    = regexp.MustCompile(`<!--.*?-->`)
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\](?:\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink   = regexp.MustCompile(`<(?:https?|ftp|mailto):[^>]*>`)
+	mdHTMLTag    = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdInlineCode = regexp.MustCompile("`+([^`]*)`+")
+	// mdEmphasis は語の境界から始まる強調です。2*3*4 のような式の * は強調とみなしません。
+	mdEmphasis    = regexp.MustCompile(`(^|[^\w*~])(\*{1,3}|~~)(\S(?:.*?\S)?)(\*{1,3}|~~)`)
+	mdUnderscores = regexp.MustCompile(`(^|[^\w])_{1,3}(\S(?:.*?\S)?)_{1,3}($|[^\w])`)
+	mdEscape      = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!|~<>])")
+)
+
+// MarkdownText は Markdown の記法を取り除き、SegmentText で台本にできるテキストに変換します。
+//
+//   - 見出しは1行の文にして、後ろに opts.HeadingPause の無音の行を挿入します。
+//   - 箇条書きと番号付きリストの項目はそれぞれ1つの文にします。
+//   - リンクは表示されるテキストだけを読み、URL と画像は読みません。
+//   - 強調、打ち消し線、インラインコードの記号は取り除きます。
+//   - コードブロックは読み飛ばすか、opts.CodeBlock の文に置き換えます。
+//   - 表は各セルを「、」でつないだ1つの文にします。
+func MarkdownText(src string, opts MarkdownOptions) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var out []string
+	// fence は閉じていないコードブロックの開始の記号です
+	var fence string
+	heading := func(text string) {
+		if text = inlineMarkdown(text); text == "" {
+			return
+		}
+		out = append(out, sentence(text))
+		if opts.HeadingPause > 0 {
+			out = append(out, fmt.Sprintf("[pause=%s]", opts.HeadingPause))
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if fence != "" {
+			if m := mdFence.FindStringSubmatch(line); m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) && strings.TrimSpace(line[len(m[0]):]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			if opts.CodeBlock != "" {
+				out = append(out, sentence(opts.CodeBlock))
+			}
+			continue
+		}
+
+		line = mdComment.ReplaceAllString(line, "")
+		switch {
+		case mdHeading.MatchString(line):
+			heading(mdHeading.FindStringSubmatch(line)[1])
+		case strings.TrimSpace(line) != "" && i+1 < len(lines) && mdSetext.MatchString(lines[i+1]) && !mdListItem.MatchString(line):
+			heading(strings.TrimSpace(line))
+			i++
+		case mdRule.MatchString(line):
+			out = append(out, "")
+		case mdLinkDef.MatchString(line), mdTableRule.MatchString(line) && strings.Contains(line, "-") && strings.Contains(line, "|"):
+			// リンクの定義と表の区切りは読まない
+		case mdListItem.MatchString(line):
+			if text := inlineMarkdown(mdListItem.ReplaceAllString(line, "")); text != "" {
+				out = append(out, sentence(text))
+			}
+		default:
+			line = mdQuote.ReplaceAllString(line, "")
+			if strings.HasPrefix(strings.TrimSpace(line), "|") {
+				out = append(out, tableRow(line))
+				continue
+			}
+			out = append(out, inlineMarkdown(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// inlineMarkdown は行の中の記法を取り除きます。
+func inlineMarkdown(s string) string {
+	// エスケープされた記号は記法として扱わないよう、いったん私用領域の文字に置き換える
+	var escaped []string
+	s = mdEscape.ReplaceAllStringFunc(s, func(m string) string {
+		escaped = append(escaped, m[1:])
+		return fmt.Sprintf("\uE000%d\uE001", len(escaped)-1)
+	})
+	s = mdInlineCode.ReplaceAllString(s, "$1")
+	s = mdImage.ReplaceAllString(s, "")
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdAutolink.ReplaceAllString(s, "")
+	s = mdHTMLTag.ReplaceAllString(s, "")
+	// 入れ子の強調(**太字の中の*斜体*** など)のため2回繰り返す
+	for range 2 {
+		s = stripEmphasis(s)
+	}
+	s = mdUnderscores.ReplaceAllString(s, "$1$2$3")
+	for i, e := range escaped {
+		s = strings.Replace(s, fmt.Sprintf("\uE000%d\uE001", i), e, 1)
+	}
+	return strings.TrimSpace(s)
+}
+
+// stripEmphasis は mdEmphasis の強調の記号を取り除きます。閉じる記号の直後に英数字が続く場合は強調とみなしません。
+func stripEmphasis(s string) string {
+	var b strings.Builder
+	prev := 0
+	for _, m := range mdEmphasis.FindAllStringSubmatchIndex(s, -1) {
+		if m[1] < len(s) && isWordByte(s[m[1]]) {
+			continue
+		}
+		b.WriteString(s[prev:m[3]])
+		b.WriteString(s[m[6]:m[7]])
+		prev = m[1]
+	}
+	b.WriteString(s[prev:])
+	return b.String()
+}
+
+// isWordByte は c が英数字か _ であるかを返します。
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// tableRow は表の1行を、セルを「、」でつないだ文にします。
+func tableRow(line string) string {
+	line = strings.Trim(strings.TrimSpace(line), "|")
+	var cells []string
+	for _, cell := range strings.Split(line, "|") {
+		if cell = inlineMarkdown(cell); cell != "" {
+			cells = append(cells, cell)
+		}
+	}
+	if len(cells) == 0 {
+		return ""
+	}
+	return sentence(strings.Join(cells, "、"))
+}
+
+// sentence は文末に句点がなければ「。」を付けます。
+func sentence(s string) string {
+	for _, end := range []string{"。", "！", "？", "!", "?"} {
+		if strings.HasSuffix(s, end) {
+			return s
+		}
+	}
+	return s + "。"
+}
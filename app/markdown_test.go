@@ -0,0 +1,107 @@
+package app
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdownText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts MarkdownOptions
+		want []string
+	}{
+		{
+			name: "見出し",
+			in:   "# 第1章 **ずんだもん**について #\n\n本文です。\n\n見出し2\n---\n本文2",
+			opts: MarkdownOptions{HeadingPause: 1500 * time.Millisecond},
+			want: []string{"第1章 ずんだもんについて。", "[pause=1.5s]", "", "本文です。", "", "見出し2。", "[pause=1.5s]", "本文2"},
+		},
+		{
+			name: "見出しの後の無音なし",
+			in:   "## まとめ",
+			want: []string{"まとめ。"},
+		},
+		{
+			name: "リスト",
+			in:   "- 枝豆の妖精\n* 語尾は「なのだ」\n  + 入れ子の項目！\n1. 一つ目\n2) 二つ目。\n- [x] 完了した作業",
+			want: []string{"枝豆の妖精。", "語尾は「なのだ」。", "入れ子の項目！", "一つ目。", "二つ目。", "完了した作業。"},
+		},
+		{
+			name: "リンクと強調",
+			in:   "[東北ずん子](https://zunko.jp/)の*マスコット*で、__緑色__の~~妖精~~`ずんだもん`です。<https://example.com>![立ち絵](a.png)\\*注\\*",
+			want: []string{"東北ずん子のマスコットで、緑色の妖精ずんだもんです。*注*"},
+		},
+		{
+			name: "式の中の*",
+			in:   "2*3*4 は24、a*b*c も*強調*もある。**FP3級**と*x*",
+			want: []string{"2*3*4 は24、a*b*c も強調もある。FP3級とx"},
+		},
+		{
+			name: "参照リンク",
+			in:   "[公式サイト][zunko]を見てね。\n\n[zunko]: https://zunko.jp/",
+			want: []string{"公式サイトを見てね。", ""},
+		},
+		{
+			name: "コードブロックを読み飛ばす",
+			in:   "前の文。\n```go\n# コメント\nfmt.Println(1)\n```\n後の文。",
+			want: []string{"前の文。", "後の文。"},
+		},
+		{
+			name: "コードブロックを置き換える",
+			in:   "~~~~\n```\n~~~~\n後の文。",
+			opts: MarkdownOptions{CodeBlock: "コードは省略します"},
+			want: []string{"コードは省略します。", "後の文。"},
+		},
+		{
+			name: "引用と表と区切り線",
+			in:   "> 引用です。\n\n***\n| 名前 | 色 |\n|:---|---:|\n| ずんだもん | 緑 |\n<!-- メモ -->",
+			want: []string{"引用です。", "", "", "名前、色。", "ずんだもん、緑。", ""},
+		},
+		{
+			name: "行頭タグと話者ラベルは残す",
+			in:   "[speed=1.2] ずんだもん: 速く読むのだ。",
+			want: []string{"[speed=1.2] ずんだもん: 速く読むのだ。"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Split(MarkdownText(tt.in, tt.opts), "\n")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateSegmentsMarkdown(t *testing.T) {
+	path := t.TempDir() + "/input.md"
+	if err := os.WriteFile(path, []byte("# はじめに\n\n- ずんだもんなのだ\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := 40
+	segments, err := CreateSegmentsMarkdown(path, &l, DefaultMarkdownOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := ParseSegments(segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, line := range script {
+		if line.Text != "" {
+			texts = append(texts, line.Text)
+		}
+	}
+	if strings.Join(texts, "/") != "はじめに。/ずんだもんなのだ。" {
+		t.Errorf("texts = %q", texts)
+	}
+	// 見出しの後の無音行
+	if script[1].Text != "" || script[1].Prosody.Pause == nil || *script[1].Prosody.Pause != DefaultHeadingPause {
+		t.Errorf("見出しの後の行 = %+v", script[1])
+	}
+}
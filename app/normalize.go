@@ -0,0 +1,360 @@
+package app
+
+import (
+	"cmp"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TextNormalizer は音声合成に送るテキストを読み上げやすい形に変換します。
+// 台本ファイルや字幕に表示するテキストは変換しません。
+//
+//   - 全角の英数字と記号を半角に、半角カタカナを全角にそろえます。
+//   - 日付(2024/01/15, 2024/01)、時刻(10:30)、金額(¥1,000, $100)、割合(5%)、範囲(1〜3)を読み方の順に並べ替えます。
+//   - 数字を漢数字(1,000,000 → 百万、3.5 → 三点五)にします。日付や順序の前の 0(01月、第01問)は読みません。
+//   - ハイフンで区切った数字の列(03-1234-5678)は1桁ずつ読みます。
+//   - 単位(km, kg など)と記号を Symbols の読み方に置き換えます。
+//
+// ユーザー辞書の単語はエンジンに送るテキストと照合されるため、数字や記号を含む表記("FP3級" など)は
+// 変換すると一致しなくなります。そのような単語は Keep で変換しないように指定します。
+type TextNormalizer struct {
+	symbols  map[string]string
+	replacer *strings.Replacer
+	// keep は変換しない語に一致する正規表現です。
+	keep *regexp.Regexp
+}
+
+// DefaultSymbols は記号の読み方の既定の対応表を返します。読み方が空の記号は読みません。
+func DefaultSymbols() map[string]string {
+	return map[string]string{
+		"&": "アンド",
+		"+": "プラス",
+		"=": "イコール",
+		"±": "プラスマイナス",
+		"℃": "度",
+		"㎡": "平方メートル",
+		"○": "マル",
+		"◯": "マル",
+		"×": "バツ",
+		"※": "",
+		"*": "",
+		"#": "",
+		"★": "",
+		"☆": "",
+		"■": "",
+		"□": "",
+		"◆": "",
+		"◇": "",
+		"●": "",
+		"→": "",
+	}
+}
+
+// units は数字の直後に書かれた単位の読み方です。
+var units = map[string]string{
+	"km": "キロメートル",
+	"m":  "メートル",
+	"cm": "センチメートル",
+	"mm": "ミリメートル",
+	"kg": "キログラム",
+	"g":  "グラム",
+}
+
+// NewTextNormalizer は DefaultSymbols に symbols を加えた対応表で記号を置き換える TextNormalizer を返します。
+// symbols に DefaultSymbols と同じ記号がある場合は symbols の読み方を使います。
+func NewTextNormalizer(symbols map[string]string) *TextNormalizer {
+	n := &TextNormalizer{symbols: DefaultSymbols()}
+	for k, v := range symbols {
+		n.symbols[foldWidth(k)] = v
+	}
+	// 長い記号から順に比べる
+	keys := slices.SortedFunc(maps.Keys(n.symbols), func(a, b string) int {
+		return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+	})
+	var pairs []string
+	for _, k := range keys {
+		if k != "" {
+			pairs = append(pairs, k, n.symbols[k])
+		}
+	}
+	n.replacer = strings.NewReplacer(pairs...)
+	return n
+}
+
+// DefaultTextNormalizer は既定の記号の対応表を使う TextNormalizer を返します。
+func DefaultTextNormalizer() *TextNormalizer {
+	return NewTextNormalizer(nil)
+}
+
+// Keep は words(ユーザー辞書の単語の表記など)を変換せずにエンジンに送るよう指定します。
+// 全角と半角の違いは区別しません。
+func (n *TextNormalizer) Keep(words ...string) {
+	var patterns []string
+	for _, w := range words {
+		if w = foldWidth(w); w != "" {
+			patterns = append(patterns, regexp.QuoteMeta(w))
+		}
+	}
+	if len(patterns) == 0 {
+		n.keep = nil
+		return
+	}
+	// 長い語を優先する
+	slices.SortFunc(patterns, func(a, b string) int { return cmp.Or(len(b)-len(a), strings.Compare(a, b)) })
+	n.keep = regexp.MustCompile(strings.Join(slices.Compact(patterns), "|"))
+}
+
+const numPattern = `\d{1,3}(?:,\d{3})+|\d+`
+
+var (
+	reDate      = regexp.MustCompile(`\b(\d{4})[/.\-](\d{1,2})[/.\-](\d{1,2})\b`)
+	reYearMonth = regexp.MustCompile(`\b(\d{4})/(\d{1,2})\b`)
+	reClock     = regexp.MustCompile(`\b(\d{1,2}):(\d{2})\b`)
+	// reDigitGroups はハイフンで区切った数字の列(電話番号など)です。区切りが2つ以上あるか、0 で始まるものを1桁ずつ読みます。
+	reDigitGroups = regexp.MustCompile(`\b(?:\d+(?:-\d+){2,}|0\d*(?:-\d+)+)\b`)
+	// reOrdinal と reCounter は、順序や日付・時刻の数の前に付けた 0(第01問、01月、05分)です。
+	reOrdinal  = regexp.MustCompile(`第0+(\d)`)
+	reCounter  = regexp.MustCompile(`0+(\d+[年月日時分秒問回号番章節条])`)
+	reCurrency = regexp.MustCompile(`([¥$])\s?((?:` + numPattern + `)(?:\.\d+)?)`)
+	rePercent  = regexp.MustCompile(`((?:` + numPattern + `)(?:\.\d+)?)\s?%`)
+	reRange    = regexp.MustCompile(`(\d)\s*[~〜～]\s*([¥$]?\d)`)
+	reMultiply = regexp.MustCompile(`(\d)\s*([×÷])\s*(\d)`)
+	reNegative = regexp.MustCompile(`(^|[^\w.])[-−](\d)`)
+	reFraction = regexp.MustCompile(`\b(\d+)/(\d+)\b`)
+	// reUnit は数字の直後の単位です。"5g通信" のように後ろに語が続く場合は単位とみなしません(助詞のひらがなは続いてもよい)。
+	reUnit   = regexp.MustCompile(`(\d)(km|kg|cm|mm|m|g)($|[^\p{L}\p{N}_]|\p{Hiragana})`)
+	reNumber = regexp.MustCompile(`(` + numPattern + `)(?:\.(\d+))?`)
+	reDigits = regexp.MustCompile(`\d+`)
+)
+
+// Normalize は text を音声合成に送るテキストに変換します。Keep で指定した語は変換しません。
+func (n *TextNormalizer) Normalize(text string) string {
+	text = foldWidth(text)
+	if n.keep == nil {
+		return n.normalize(text)
+	}
+	var b strings.Builder
+	prev := 0
+	for _, loc := range n.keep.FindAllStringIndex(text, -1) {
+		b.WriteString(n.normalize(text[prev:loc[0]]))
+		b.WriteString(text[loc[0]:loc[1]])
+		prev = loc[1]
+	}
+	b.WriteString(n.normalize(text[prev:]))
+	return b.String()
+}
+
+// normalize は全角と半角をそろえた text を変換します。
+func (n *TextNormalizer) normalize(text string) string {
+	text = reDate.ReplaceAllStringFunc(text, func(s string) string {
+		m := reDate.FindStringSubmatch(s)
+		y, mo, d := atoi(m[1]), atoi(m[2]), atoi(m[3])
+		if mo < 1 || mo > 12 || d < 1 || d > 31 {
+			// 日付でない場合も分数などとして読まないよう1桁ずつ読む
+			return digitRuns(s)
+		}
+		return kanjiNumber(y) + "年" + kanjiNumber(mo) + "月" + kanjiNumber(d) + "日"
+	})
+	text = reYearMonth.ReplaceAllStringFunc(text, func(s string) string {
+		m := reYearMonth.FindStringSubmatch(s)
+		y, mo := atoi(m[1]), atoi(m[2])
+		if mo < 1 || mo > 12 {
+			return digitRuns(s)
+		}
+		return kanjiNumber(y) + "年" + kanjiNumber(mo) + "月"
+	})
+	text = reDigitGroups.ReplaceAllStringFunc(text, func(s string) string {
+		groups := strings.Split(s, "-")
+		for i, g := range groups {
+			groups[i] = digitByDigit(g)
+		}
+		return strings.Join(groups, "の")
+	})
+	text = reOrdinal.ReplaceAllString(text, "第${1}")
+	text = trimCounterZeros(text)
+	// 時刻の範囲(10:30〜11:00)も「から」にするため、時刻より先に変換する
+	text = reRange.ReplaceAllString(text, "${1}から${2}")
+	text = reClock.ReplaceAllStringFunc(text, func(s string) string {
+		m := reClock.FindStringSubmatch(s)
+		h, mi := atoi(m[1]), atoi(m[2])
+		switch {
+		case h > 24 || mi > 59:
+			return s
+		case mi == 0:
+			return kanjiNumber(h) + "時"
+		}
+		return kanjiNumber(h) + "時" + kanjiNumber(mi) + "分"
+	})
+	text = reCurrency.ReplaceAllStringFunc(text, func(s string) string {
+		m := reCurrency.FindStringSubmatch(s)
+		if m[1] == "$" {
+			return m[2] + "ドル"
+		}
+		return m[2] + "円"
+	})
+	text = rePercent.ReplaceAllString(text, "${1}パーセント")
+	text = reMultiply.ReplaceAllStringFunc(text, func(s string) string {
+		m := reMultiply.FindStringSubmatch(s)
+		if m[2] == "×" {
+			return m[1] + "かける" + m[3]
+		}
+		return m[1] + "わる" + m[3]
+	})
+	text = reNegative.ReplaceAllString(text, "${1}マイナス${2}")
+	text = reFraction.ReplaceAllString(text, "${2}分の${1}")
+	text = reUnit.ReplaceAllStringFunc(text, func(s string) string {
+		m := reUnit.FindStringSubmatch(s)
+		return m[1] + units[m[2]] + m[3]
+	})
+	text = reNumber.ReplaceAllStringFunc(text, func(s string) string {
+		m := reNumber.FindStringSubmatch(s)
+		r := kanjiDigits(strings.ReplaceAll(m[1], ",", ""))
+		if m[2] != "" {
+			r += "点" + digitByDigit(m[2])
+		}
+		return r
+	})
+	return n.replacer.Replace(text)
+}
+
+// trimCounterZeros は reCounter に一致する数の前の 0 を取り除きます。
+// 前に数字が続く場合(100月 など)は位の 0 なので残します。
+func trimCounterZeros(text string) string {
+	var b strings.Builder
+	prev := 0
+	for _, m := range reCounter.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > 0 && strings.IndexByte("0123456789.,", text[m[0]-1]) >= 0 {
+			continue
+		}
+		b.WriteString(text[prev:m[0]])
+		prev = m[2]
+	}
+	b.WriteString(text[prev:])
+	return b.String()
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+var (
+	kanjiDigit = []string{"ゼロ", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+	// kanjiUnits は4桁ごとの位です。
+	kanjiUnits = []string{"", "万", "億", "兆", "京"}
+)
+
+// kanjiNumber は n を漢数字にします。
+func kanjiNumber(n int) string {
+	return kanjiDigits(strconv.Itoa(n))
+}
+
+// kanjiDigits は数字の列 s を漢数字(1234 → 千二百三十四)にします。
+// 0 で始まる列と、京の位を超える列は1桁ずつ読みます。
+func kanjiDigits(s string) string {
+	if s == "0" {
+		return kanjiDigit[0]
+	}
+	if s[0] == '0' || len(s) > 4*len(kanjiUnits) {
+		return digitByDigit(s)
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		// 上の位から4桁ずつ
+		group := (len(s) - i - 1) / 4
+		end := len(s) - group*4
+		if g := smallNumber(s[i:end]); g != "" {
+			b.WriteString(g + kanjiUnits[group])
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// smallNumber は4桁以下の数字の列を漢数字にします。0 の場合は空文字列です。
+func smallNumber(s string) string {
+	var b strings.Builder
+	for i, c := range s {
+		d := int(c - '0')
+		if d == 0 {
+			continue
+		}
+		unit := []string{"", "十", "百", "千"}[len(s)-i-1]
+		if d > 1 || unit == "" {
+			b.WriteString(kanjiDigit[d])
+		}
+		b.WriteString(unit)
+	}
+	return b.String()
+}
+
+// digitByDigit は数字の列を1桁ずつ漢数字にします。
+func digitByDigit(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		b.WriteString(kanjiDigit[c-'0'])
+	}
+	return b.String()
+}
+
+// digitRuns は s の中の数字の列をそれぞれ1桁ずつ漢数字にします。
+func digitRuns(s string) string {
+	return reDigits.ReplaceAllStringFunc(s, digitByDigit)
+}
+
+// foldedSymbols は全角から半角にそろえる記号です。括弧や感嘆符などはそのまま残します。
+const foldedSymbols = "％＄＆＋－＝／：．，＃＊＠～"
+
+// 半角カタカナと、対応する全角カタカナ
+const (
+	halfKatakana = "｡｢｣､･ｦｧｨｩｪｫｬｭｮｯｰｱｲｳｴｵｶｷｸｹｺｻｼｽｾｿﾀﾁﾂﾃﾄﾅﾆﾇﾈﾉﾊﾋﾌﾍﾎﾏﾐﾑﾒﾓﾔﾕﾖﾗﾘﾙﾚﾛﾜﾝ"
+	fullKatakana = "。「」、・ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン"
+)
+
+var halfToFull = func() map[rune]rune {
+	m := map[rune]rune{}
+	full := []rune(fullKatakana)
+	for i, r := range []rune(halfKatakana) {
+		m[r] = full[i]
+	}
+	return m
+}()
+
+// foldWidth は全角の英数字と一部の記号を半角に、半角カタカナを全角にそろえます。
+func foldWidth(s string) string {
+	var out []rune
+	for _, r := range s {
+		switch {
+		case r >= '０' && r <= '９', r >= 'Ａ' && r <= 'Ｚ', r >= 'ａ' && r <= 'ｚ', strings.ContainsRune(foldedSymbols, r):
+			r -= 0xFEE0
+		case r == '￥':
+			r = '¥'
+		case r == 'ﾞ' && len(out) > 0:
+			// 濁点は前の文字と合わせる
+			switch prev := out[len(out)-1]; {
+			case prev == 'ウ':
+				out[len(out)-1] = 'ヴ'
+				continue
+			case strings.ContainsRune("カキクケコサシスセソタチツテトハヒフヘホ", prev):
+				out[len(out)-1] = prev + 1
+				continue
+			}
+			r = '゛'
+		case r == 'ﾟ' && len(out) > 0:
+			if prev := out[len(out)-1]; strings.ContainsRune("ハヒフヘホ", prev) {
+				out[len(out)-1] = prev + 2
+				continue
+			}
+			r = '゜'
+		default:
+			if f, ok := halfToFull[r]; ok {
+				r = f
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"金額", "貯蓄は1,000,000円です。", "貯蓄は百万円です。"},
+		{"大きな数", "12345678901円", "百二十三億四千五百六十七万八千九百一円"},
+		{"万の位が0", "100000020", "一億二十"},
+		{"年月", "2024/01の試験", "二千二十四年一月の試験"},
+		{"年月日", "2024-04-01に改正", "二千二十四年四月一日に改正"},
+		{"月でない数", "2024/13", "二ゼロ二四/一三"},
+		{"日付でない数", "2024/13/45", "二ゼロ二四/一三/四五"},
+		{"時刻", "10:30から11:00まで", "十時三十分から十一時まで"},
+		{"円記号", "¥5,000と$100", "五千円と百ドル"},
+		{"割合", "利率は3.5%です", "利率は三点五パーセントです"},
+		{"小数", "0.05", "ゼロ点ゼロ五"},
+		{"範囲", "1〜3割", "一から三割"},
+		{"分数", "1/2", "二分の一"},
+		{"負の数", "-5度", "マイナス五度"},
+		{"ハイフンの区切り", "A-1", "A-一"},
+		{"0で始まる数", "007", "ゼロゼロ七"},
+		{"単位", "5kgと10mm", "五キログラムと十ミリメートル"},
+		{"単位の後の句読点", "5m、10cm。", "五メートル、十センチメートル。"},
+		{"単位でない英字", "5g通信とSDカード", "五g通信とSDカード"},
+		{"前に0を付けた年月", "2024年01月", "二千二十四年一月"},
+		{"前に0を付けた順序", "第01問", "第一問"},
+		{"位の0", "100日", "百日"},
+		{"前に0を付けた時刻", "09時05分", "九時五分"},
+		{"電話番号", "03-1234-5678", "ゼロ三の一二三四の五六七八"},
+		{"0で始まる番号", "0120-12-345", "ゼロ一二ゼロの一二の三四五"},
+		{"時刻の範囲", "10:30〜11:00", "十時三十分から十一時"},
+		{"掛け算", "3×4", "三かける四"},
+		{"記号", "○か×か※注意", "マルかバツか注意"},
+		{"全角", "ＦＰ３級は１０％", "FP三級は十パーセント"},
+		{"半角カタカナ", "ﾊﾟｿｺﾝとﾃﾞｰﾀ", "パソコンとデータ"},
+		{"そのまま", "こんにちは、世界！", "こんにちは、世界！"},
+	}
+	n := DefaultTextNormalizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSymbols(t *testing.T) {
+	n := NewTextNormalizer(map[string]string{"＆": "と", "→": "から", "FP": "エフピー"})
+	if got, want := n.Normalize("A&B→C、FP"), "AとBからC、エフピー"; got != want {
+		t.Errorf("Normalize() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeKeep(t *testing.T) {
+	n := DefaultTextNormalizer()
+	n.Keep("ＦＰ３級", "C++", "")
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// ユーザー辞書の単語は変換せずに送り、エンジンで辞書と照合させる
+		{"FP3級の試験は2級の前", "FP3級の試験は二級の前"},
+		{"ＦＰ３級とC++と3+4", "FP3級とC++と三プラス四"},
+		{"FP2級", "FP二級"},
+	}
+	for _, tt := range tests {
+		if got := n.Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSynthesizeNormalized(t *testing.T) {
+	engine := newFakeEngine(t)
+	synth := NewSynthesizer(engine.client(t))
+	line := ScriptLine{Number: 1, Text: "1,000円"}
+	_, query, err := synth.SynthesizeQuery(context.Background(), line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fakeEngine は送られたテキストを Kana に入れて返す
+	if query.Kana != "千円" {
+		t.Errorf("エンジンに送ったテキスト = %q, want %q", query.Kana, "千円")
+	}
+	if line.Text != "1,000円" {
+		t.Errorf("台本の本文が変わっています: %q", line.Text)
+	}
+}
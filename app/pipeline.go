@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultWorkers は同時に音声合成する行数の既定値です。
+// voicevox自体にそれほど処理スピードがないため、少なめにしておく。
+const DefaultWorkers = 3
+
+// LineResult は台本の1行の音声合成の結果です。
+type LineResult struct {
+	// Index は台本の中の位置(0から)です。
+	Index int
+	Line  ScriptLine
+	// Audio はWAVファイルのバイト列です。Err が nil でない場合は nil です。
+	Audio []byte
+	// Query は合成に使った音声クエリです。無音行の場合と、Pipeline.Queries が false で
+	// 保存済みの音声を再利用した場合は nil です。
+	Query *AudioQuery
+	Err   error
+}
+
+// Pipeline は台本の行を Workers 行ずつ並行して音声合成し、結果を台本の順に返します。
+type Pipeline struct {
+	Synth *Synthesizer
+	// Workers は同時に音声合成する行数です。0以下の場合は DefaultWorkers です。
+	Workers int
+	// Dir が空でない場合、各行の音声を Dir/00000.wav, Dir/00001.wav, ... に保存します。
+	Dir string
+	// Resume が true の場合は Dir を削除せず、前回保存した内容の変わらない行を再利用します。
+	Resume bool
+	// Queries が true の場合、保存済みの音声を再利用した行でも音声クエリを生成し直して LineResult.Query に設定します。
+	Queries bool
+}
+
+// LinePath は Dir に保存する i 行目(0から)の音声ファイルのパスを返します。
+func (p *Pipeline) LinePath(i int) string {
+	return filepath.Join(p.Dir, fmt.Sprintf("%05d.wav", i))
+}
+
+// Run は lines の音声合成を開始し、結果を台本の順に返すイテレーターを返します。
+//
+// 結果を受け取り終える前にループを抜けた場合や ctx がキャンセルされた場合は、処理中の行を中断します。
+// キャンセルされた場合は、未完了の最初の行の結果として ctx のエラーを返して終了します。
+// 中断した行の音声ファイルは保存されません。
+func (p *Pipeline) Run(ctx context.Context, lines []ScriptLine) (iter.Seq[LineResult], error) {
+	todo := make([]bool, len(lines))
+	if p.Dir == "" {
+		for i := range todo {
+			todo[i] = true
+		}
+	} else {
+		signatures := make([]string, len(lines))
+		for i, line := range lines {
+			signatures[i] = p.Synth.signature(line)
+		}
+		indices, err := PrepareLineDir(p.Dir, signatures, p.LinePath, p.Resume)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range indices {
+			todo[i] = true
+		}
+	}
+
+	return func(yield func(LineResult) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer cancel()
+
+		workers := p.Workers
+		if workers <= 0 {
+			workers = DefaultWorkers
+		}
+		// 先の行ばかり合成して結果が溜まらないよう、受け取られていない結果の数を制限する
+		window := make(chan struct{}, 2*workers)
+		jobs := make(chan int)
+		results := make(chan LineResult, cap(window))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(jobs)
+			for i := range lines {
+				select {
+				case window <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case jobs <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					r := LineResult{Index: i, Line: lines[i]}
+					r.Audio, r.Query, r.Err = p.synthesize(ctx, i, lines[i], todo[i])
+					select {
+					case results <- r:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		pending := make(map[int]LineResult)
+		for next := 0; next < len(lines); {
+			r, ok := pending[next]
+			if !ok {
+				select {
+				case r := <-results:
+					pending[r.Index] = r
+				case <-ctx.Done():
+					yield(LineResult{Index: next, Line: lines[next], Err: ctx.Err()})
+					return
+				}
+				continue
+			}
+			delete(pending, next)
+			<-window
+			next++
+			if !yield(r) {
+				return
+			}
+		}
+	}, nil
+}
+
+// synthesize は i 行目の音声とクエリを返します。synth が false の場合は Dir に保存済みの音声を読み込みます。
+func (p *Pipeline) synthesize(ctx context.Context, i int, line ScriptLine, synth bool) ([]byte, *AudioQuery, error) {
+	if !synth {
+		data, err := os.ReadFile(p.LinePath(i))
+		if err != nil || !p.Queries || line.Text == "" {
+			return data, nil, err
+		}
+		query, err := p.Synth.Query(ctx, line)
+		return data, query, err
+	}
+	data, query, err := p.Synth.SynthesizeQuery(ctx, line)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.Dir != "" {
+		// 中断しても書きかけのファイルが残らないよう、一時ファイルに書いてから名前を変える
+		if err := writeFileAtomic(p.LinePath(i), data); err != nil {
+			return nil, nil, fmt.Errorf("音声データをファイルに書き込む際にエラーが発生しました: %w", err)
+		}
+	}
+	return data, query, nil
+}
+
+// SynthesizeAll は lines の全ての行を音声合成して Dir に保存します。
+// 失敗した行があっても残りの行の音声合成は続け、失敗した全ての行を *SynthesisError で報告します。
+// ctx がキャンセルされた場合は ctx のエラーを返します。
+func (p *Pipeline) SynthesizeAll(ctx context.Context, lines []ScriptLine) error {
+	if p.Dir == "" {
+		return errors.New("音声の保存先のディレクトリが指定されていません")
+	}
+	results, err := p.Run(ctx, lines)
+	if err != nil {
+		return err
+	}
+	return Drain(ctx, results, nil)
+}
+
+// Drain は results を最後まで受け取り、成功した結果ごとに台本の順に f を呼びます(f は nil でも構いません)。
+// 失敗した行があった後は f を呼ばずに残りの結果を受け取り、失敗した全ての行を *SynthesisError にまとめて返します。
+// f がエラーを返した場合や ctx がキャンセルされた場合はそのエラーを返します。
+func Drain(ctx context.Context, results iter.Seq[LineResult], f func(LineResult) error) error {
+	var (
+		failed []*LineError
+		total  int
+	)
+	for r := range results {
+		total++
+		if r.Err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failed = append(failed, &LineError{Index: r.Index, Line: r.Line, Err: r.Err})
+			continue
+		}
+		if f != nil && len(failed) == 0 {
+			if err := f(r); err != nil {
+				return err
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return &SynthesisError{Total: total, Failed: failed}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func pipelineScript(t *testing.T, texts ...string) []ScriptLine {
+	t.Helper()
+	lines, err := ParseScript(texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range lines {
+		lines[i].SpeakerID = 1
+	}
+	return lines
+}
+
+func TestPipelineOrder(t *testing.T) {
+	engine := newFakeEngine(t)
+	// 先の行ほど時間がかかるようにして、完了の順と台本の順を逆にする
+	delays := map[string]time.Duration{"一。": 60 * time.Millisecond, "二。": 40 * time.Millisecond, "三。": 20 * time.Millisecond}
+	engine.delay = func(text string) time.Duration { return delays[text] }
+	lines := pipelineScript(t, "一。", "二。", "", "三。", "四。")
+
+	p := &Pipeline{Synth: NewSynthesizer(engine.client(t)), Workers: 4}
+	results, err := p.Run(context.Background(), lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := 0
+	err = Drain(context.Background(), results, func(r LineResult) error {
+		if r.Index != next {
+			t.Errorf("Index = %d, want %d", r.Index, next)
+		}
+		if r.Line.Text != lines[next].Text || len(r.Audio) == 0 {
+			t.Errorf("%d: Line = %q, len(Audio) = %d", next, r.Line.Text, len(r.Audio))
+		}
+		if (r.Query != nil) != (r.Line.Text != "") {
+			t.Errorf("%d: Query = %v", next, r.Query)
+		}
+		next++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != len(lines) {
+		t.Errorf("%d 行の結果, want %d", next, len(lines))
+	}
+}
+
+func TestPipelineFailure(t *testing.T) {
+	engine := newFakeEngine(t)
+	engine.failStatus = 422
+	engine.failures.Store(1)
+	lines := pipelineScript(t, "一。", "二。", "三。")
+
+	p := &Pipeline{Synth: NewSynthesizer(engine.client(t)), Workers: 1, Dir: t.TempDir()}
+	err := p.SynthesizeAll(context.Background(), lines)
+	var serr *SynthesisError
+	if !errors.As(err, &serr) {
+		t.Fatalf("err = %v, want *SynthesisError", err)
+	}
+	if serr.Total != 3 || len(serr.Failed) != 1 || serr.Failed[0].Index != 0 {
+		t.Errorf("err = %v", err)
+	}
+	// 失敗した行があっても残りの行は保存する
+	for _, i := range []int{1, 2} {
+		if !isCompleteWAV(p.LinePath(i)) {
+			t.Errorf("%d 行目が保存されていない", i)
+		}
+	}
+}
+
+func TestPipelineCancel(t *testing.T) {
+	engine := newFakeEngine(t)
+	engine.delay = func(text string) time.Duration {
+		if text == "一。" {
+			return 0
+		}
+		return time.Minute
+	}
+	lines := pipelineScript(t, "一。", "二。", "三。", "四。")
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &Pipeline{Synth: NewSynthesizer(engine.client(t)), Workers: 2, Dir: dir}
+	results, err := p.Run(ctx, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	err = Drain(ctx, results, func(r LineResult) error {
+		got = append(got, r.Index)
+		// 最初の行を受け取ったところで中断する
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if len(got) != 1 || got[0] != 0 {
+		t.Errorf("受け取った行 = %v", got)
+	}
+
+	// 保存されたのは完了した行だけで、書きかけのファイルは残らない
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		switch e.Name() {
+		case linesFile, filepath.Base(p.LinePath(0)):
+		default:
+			t.Errorf("中断後に %s が残っている", e.Name())
+		}
+	}
+
+	// 再開すると完了した行は合成しない
+	engine = newFakeEngine(t)
+	p = &Pipeline{Synth: NewSynthesizer(engine.client(t)), Workers: 2, Dir: dir, Resume: true}
+	if err := p.SynthesizeAll(context.Background(), lines); err != nil {
+		t.Fatal(err)
+	}
+	if n := engine.queries.Load(); n != 3 {
+		t.Errorf("再開時に %d 行を音声合成, want 3", n)
+	}
+
+	// Queries を指定した場合は再利用した行の音声クエリも取得する
+	p.Queries = true
+	results, err = p.Run(context.Background(), lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Drain(context.Background(), results, func(r LineResult) error {
+		if r.Query == nil {
+			t.Errorf("%d 行目の Query が nil", r.Index)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := engine.syntheses.Load(); n != 3 {
+		t.Errorf("保存済みの行を再合成した: syntheses = %d", n)
+	}
+}
@@ -0,0 +1,456 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"voicevox/wav"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Project は1つのナレーションの作成手順をまとめたプロジェクトファイルです。
+// 相対パスはプロジェクトファイルのあるディレクトリからのパスとして扱います。
+//
+//	name: g3_202401_q
+//	inputs:
+//	  - in/*.txt
+//	script:
+//	  length: 40
+//	speaker: ずんだもん/ノーマル
+//	speakers_file: speakers.yaml
+//	dictionary: dictionary.yaml
+//	silence:
+//	  sentence: 400ms
+//	  paragraph: 700ms
+//	output:
+//	  dir: out
+//	  name: "{name}"
+//	  join: all
+//	post:
+//	  sentence_gap: 200ms
+//	  normalize:
+//	    mode: loudness
+//	    lufs: -16
+type Project struct {
+	// Name はプロジェクトの名前です。省略した場合はプロジェクトファイルの名前になります。
+	Name string `yaml:"name"`
+	// Engine はVOICEVOXエンジンのURLです。省略した場合は EngineURL() を使います。
+	Engine string `yaml:"engine"`
+	// Inputs は入力するテキストファイルです。glob のパターンも指定できます。
+	Inputs []string      `yaml:"inputs"`
+	Script ProjectScript `yaml:"script"`
+
+	// Speaker は話者ラベルのない行に使うスタイルです。
+	Speaker StyleRef `yaml:"speaker"`
+	// Speakers は話者ラベルとスタイルの対応表です。
+	Speakers *SpeakerConfig `yaml:"speakers"`
+	// SpeakersFile は話者ラベルとスタイルの対応表のファイルです。Speakers と同時には指定できません。
+	SpeakersFile string `yaml:"speakers_file"`
+	// Dictionary は読み方の辞書ファイルです。指定した場合は音声合成の前にエンジンのユーザー辞書に登録します。
+	Dictionary string `yaml:"dictionary"`
+
+	Silence ProjectSilence `yaml:"silence"`
+	Output  ProjectOutput  `yaml:"output"`
+	Post    ProjectPost    `yaml:"post"`
+	Cache   ProjectCache   `yaml:"cache"`
+	Retry   ProjectRetry   `yaml:"retry"`
+
+	// dir はプロジェクトファイルのあるディレクトリです。
+	dir string
+}
+
+// ProjectScript は台本の作成方法です。
+type ProjectScript struct {
+	// Length は台本の1行の最大文字数の目安です。
+	Length int `yaml:"length"`
+	// Prepared が true の場合は入力を整形済みの台本として扱い、Create を通しません。
+	Prepared bool `yaml:"prepared"`
+	// Format は入力ファイルの形式(auto, text, markdown, aozora)です。省略した場合は拡張子から判定します。
+	Format string `yaml:"format"`
+	// Encoding は入力ファイルの文字コード(auto, utf-8, shift_jis, euc-jp)です。省略した場合は内容から判定します。
+	Encoding string `yaml:"encoding"`
+	// Markdown は Markdown ファイルの入力の変換の設定です。
+	Markdown ProjectMarkdown `yaml:"markdown"`
+	// Aozora は青空文庫形式の入力の変換の設定です。
+	Aozora ProjectAozora `yaml:"aozora"`
+	// Normalize は音声合成に送るテキストの変換の設定です。
+	Normalize ProjectTextNormalize `yaml:"normalize"`
+}
+
+// ProjectAozora は青空文庫形式の入力の変換の設定です。省略した項目は DefaultAozoraOptions の値を使います。
+type ProjectAozora struct {
+	// PagePause は改ページの位置の無音の長さです。
+	PagePause *time.Duration `yaml:"page_pause"`
+	// HeadingPause は見出しの後の無音の長さです。
+	HeadingPause *time.Duration `yaml:"heading_pause"`
+}
+
+// ProjectTextNormalize は音声合成に送るテキストの変換(数字や記号の読み方)の設定です。
+type ProjectTextNormalize struct {
+	// Disabled が true の場合は変換せずにエンジンに送ります。
+	Disabled bool `yaml:"disabled"`
+	// Symbols は既定の対応表に加える記号と読み方の対応です。
+	Symbols map[string]string `yaml:"symbols"`
+}
+
+// ProjectMarkdown は Markdown ファイルの入力の変換の設定です。
+type ProjectMarkdown struct {
+	// HeadingPause は見出しの後の無音の長さです。省略した場合は DefaultHeadingPause です。
+	HeadingPause *time.Duration `yaml:"heading_pause"`
+	// CodeBlock はコードブロックの代わりに読み上げる文です。省略した場合は読み飛ばします。
+	CodeBlock string `yaml:"code_block"`
+}
+
+// ProjectSilence は無音行の長さです。
+type ProjectSilence struct {
+	Sentence  time.Duration `yaml:"sentence"`
+	Paragraph time.Duration `yaml:"paragraph"`
+}
+
+// ProjectOutput は出力先の設定です。
+type ProjectOutput struct {
+	// Dir は台本と結合した音声の出力ディレクトリです。
+	Dir string `yaml:"dir"`
+	// Tmp を指定した場合、1行ごとの音声を <Tmp>/<出力ファイル名>/ にも保存します。
+	// 省略した場合は行ごとの音声をファイルに保存せず、メモリ上で結合します。再開(-resume)には必要です。
+	Tmp string `yaml:"tmp"`
+	// Name は入力ごとの出力ファイル名(拡張子なし)です。
+	// {name} は入力ファイルの名前、{index} は入力の順番(1から)、{project} はプロジェクト名に置き換えます。
+	Name string `yaml:"name"`
+	// Join を指定した場合、全ての入力の音声を結合したファイルを <Dir>/<Join>.wav に保存します。
+	// 入力ごとの出力と同じ名前は指定できません。音量の正規化は結合したファイルだけに行います。
+	Join string `yaml:"join"`
+	// Workers は同時に音声合成する行数です。
+	Workers int `yaml:"workers"`
+	// Subtitles は結合したWAVファイルと一緒に保存する字幕の形式(srt, vtt)です。
+	Subtitles []string `yaml:"subtitles"`
+	// Timing は結合したWAVファイルと一緒に保存するモーラのタイミングの形式(json, csv)です。
+	Timing []string `yaml:"timing"`
+}
+
+// ProjectCache は合成した音声のキャッシュの設定です。
+type ProjectCache struct {
+	// Dir はキャッシュディレクトリです。省略した場合は DefaultCacheDir を使います。
+	Dir string `yaml:"dir"`
+	// Disabled が true の場合はキャッシュを使いません。
+	Disabled bool `yaml:"disabled"`
+}
+
+// ProjectRetry はエンジンへのリクエストが一時的に失敗した場合の再試行の設定です。
+// 省略した項目は DefaultRetryPolicy の値を使います。
+type ProjectRetry struct {
+	// Attempts は再試行する回数です(最初の1回を含みません)。
+	Attempts *int          `yaml:"attempts"`
+	Delay    time.Duration `yaml:"delay"`
+	MaxDelay time.Duration `yaml:"max_delay"`
+}
+
+// ProjectPost は結合と後処理の設定です。
+type ProjectPost struct {
+	Gap            time.Duration    `yaml:"gap"`
+	SentenceGap    time.Duration    `yaml:"sentence_gap"`
+	CommaGap       time.Duration    `yaml:"comma_gap"`
+	Crossfade      time.Duration    `yaml:"crossfade"`
+	CrossfadeCurve string           `yaml:"crossfade_curve"`
+	Normalize      ProjectNormalize `yaml:"normalize"`
+}
+
+// ProjectNormalize は音量の正規化の設定です。省略した目標値は wav パッケージの既定値を使います。
+type ProjectNormalize struct {
+	// Mode は none, peak, loudness のいずれかです。
+	Mode     string   `yaml:"mode"`
+	Peak     *float64 `yaml:"peak"`
+	Loudness *float64 `yaml:"lufs"`
+	TruePeak *float64 `yaml:"true_peak"`
+}
+
+// プロジェクトファイルで省略された項目の既定値
+const (
+	DefaultLineLength = 40
+	DefaultSpeaker    = StyleRef("1") // ずんだもん/ノーマル
+)
+
+// LoadProject はYAML形式のプロジェクトファイルを読み込み、省略された項目に既定値を設定します。
+func LoadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("プロジェクトファイルの読み込みに失敗しました: %w", err)
+	}
+	var p Project
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("プロジェクトファイルの解析に失敗しました: %s: %w", path, err)
+	}
+	p.dir = filepath.Dir(path)
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	p.setDefaults()
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("プロジェクトファイル %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Project) setDefaults() {
+	if p.Script.Length == 0 {
+		p.Script.Length = DefaultLineLength
+	}
+	if p.Speaker == "" {
+		p.Speaker = DefaultSpeaker
+	}
+	if p.Silence.Sentence == 0 {
+		p.Silence.Sentence = DefaultSentencePause
+	}
+	if p.Silence.Paragraph == 0 {
+		p.Silence.Paragraph = DefaultParagraphPause
+	}
+	if p.Output.Dir == "" {
+		p.Output.Dir = "out"
+	}
+	if p.Output.Name == "" {
+		p.Output.Name = "{name}"
+	}
+	if p.Output.Workers == 0 {
+		p.Output.Workers = DefaultWorkers
+	}
+}
+
+func (p *Project) validate() error {
+	if len(p.Inputs) == 0 {
+		return fmt.Errorf("inputs に入力ファイルを指定してください")
+	}
+	if p.Script.Length < 0 {
+		return fmt.Errorf("script.length は正の値を指定してください: %d", p.Script.Length)
+	}
+	if p.Speakers != nil && p.SpeakersFile != "" {
+		return fmt.Errorf("speakers と speakers_file は同時に指定できません")
+	}
+	if p.Speakers != nil {
+		if err := p.Speakers.validate(); err != nil {
+			return fmt.Errorf("speakers: %w", err)
+		}
+	}
+	if p.Retry.Attempts != nil && *p.Retry.Attempts < 0 {
+		return fmt.Errorf("retry.attempts は0以上の値を指定してください: %d", *p.Retry.Attempts)
+	}
+	if _, err := ParseInputFormat(p.Script.Format); err != nil {
+		return fmt.Errorf("script.format: %w", err)
+	}
+	if _, err := ParseEncoding(p.Script.Encoding); err != nil {
+		return fmt.Errorf("script.encoding: %w", err)
+	}
+	if p.Output.Workers < 0 {
+		return fmt.Errorf("output.workers は正の値を指定してください: %d", p.Output.Workers)
+	}
+	if _, err := p.subtitleFormats(); err != nil {
+		return fmt.Errorf("output.subtitles: %w", err)
+	}
+	if _, err := p.timingFormats(); err != nil {
+		return fmt.Errorf("output.timing: %w", err)
+	}
+	if _, err := p.ConcatOptions(); err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	if p.Output.Join != "" {
+		// 入力ファイルがまだない場合は実行時にエラーにする
+		inputs, _ := p.InputFiles()
+		for i, input := range inputs {
+			if p.OutputName(input, i) == p.Output.Join {
+				return fmt.Errorf("output.join の %q が入力 %s の出力ファイル名と同じです", p.Output.Join, input)
+			}
+		}
+	}
+	return nil
+}
+
+// Path はプロジェクトファイルからの相対パスを解決します。
+func (p *Project) Path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.dir, path)
+}
+
+// InputFiles は inputs のパターンに一致するファイルを返します。
+// パターンごとに名前順に並べ、同じファイルは最初の1回だけ含めます。
+func (p *Project) InputFiles() ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	for _, pattern := range p.Inputs {
+		matches, err := filepath.Glob(p.Path(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("inputs のパターンが不正です: %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("inputs のパターンに一致するファイルがありません: %s", pattern)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+	return files, nil
+}
+
+// SpeakerConfig は話者ラベルとスタイルの対応表を返します。指定がない場合は nil を返します。
+func (p *Project) SpeakerConfig() (*SpeakerConfig, error) {
+	if p.SpeakersFile != "" {
+		return LoadSpeakerConfig(p.Path(p.SpeakersFile))
+	}
+	return p.Speakers, nil
+}
+
+// LoadDictionary は読み方の辞書を読み込みます。指定がない場合は nil を返します。
+func (p *Project) LoadDictionary() (*Dictionary, error) {
+	if p.Dictionary == "" {
+		return nil, nil
+	}
+	return LoadDictionary(p.Path(p.Dictionary))
+}
+
+// TextNormalizer は音声合成に送るテキストの変換を返します。変換しない場合は nil を返します。
+func (p *Project) TextNormalizer() *TextNormalizer {
+	if p.Script.Normalize.Disabled {
+		return nil
+	}
+	return NewTextNormalizer(p.Script.Normalize.Symbols)
+}
+
+// CacheDir はキャッシュディレクトリを返します。キャッシュを使わない場合は空文字列を返します。
+func (p *Project) CacheDir() (string, error) {
+	if p.Cache.Disabled {
+		return "", nil
+	}
+	if p.Cache.Dir != "" {
+		return p.Path(p.Cache.Dir), nil
+	}
+	return DefaultCacheDir()
+}
+
+// MarkdownOptions は Markdown ファイルの入力の変換の設定を返します。
+func (p *Project) MarkdownOptions() MarkdownOptions {
+	opts := DefaultMarkdownOptions()
+	if p.Script.Markdown.HeadingPause != nil {
+		opts.HeadingPause = *p.Script.Markdown.HeadingPause
+	}
+	opts.CodeBlock = p.Script.Markdown.CodeBlock
+	return opts
+}
+
+// InputOptions は入力ファイルの変換の設定を返します。
+func (p *Project) InputOptions() InputOptions {
+	opts := DefaultInputOptions()
+	opts.Format, _ = ParseInputFormat(p.Script.Format)
+	opts.Encoding, _ = ParseEncoding(p.Script.Encoding)
+	opts.Markdown = p.MarkdownOptions()
+	if p.Script.Aozora.PagePause != nil {
+		opts.Aozora.PagePause = *p.Script.Aozora.PagePause
+	}
+	if p.Script.Aozora.HeadingPause != nil {
+		opts.Aozora.HeadingPause = *p.Script.Aozora.HeadingPause
+	}
+	return opts
+}
+
+// RetryPolicy は再試行の設定を返します。
+func (p *Project) RetryPolicy() RetryPolicy {
+	r := DefaultRetryPolicy()
+	if p.Retry.Attempts != nil {
+		r.MaxAttempts = *p.Retry.Attempts + 1
+	}
+	if p.Retry.Delay > 0 {
+		r.InitialDelay = p.Retry.Delay
+	}
+	if p.Retry.MaxDelay > 0 {
+		r.MaxDelay = p.Retry.MaxDelay
+	}
+	return r
+}
+
+// SilenceOptions は無音行の設定を返します。
+func (p *Project) SilenceOptions() SilenceOptions {
+	o := DefaultSilenceOptions()
+	o.Sentence = p.Silence.Sentence
+	o.Paragraph = p.Silence.Paragraph
+	return o
+}
+
+// OutputName は index 番目(0から)の入力 input の出力ファイル名(拡張子なし)を返します。
+func (p *Project) OutputName(input string, index int) string {
+	name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	return strings.NewReplacer(
+		"{name}", name,
+		"{index}", strconv.Itoa(index+1),
+		"{project}", p.Name,
+	).Replace(p.Output.Name)
+}
+
+// subtitleFormats は output.subtitles の字幕の形式を返します。
+func (p *Project) subtitleFormats() ([]SubtitleFormat, error) {
+	return ParseSubtitleFormats(strings.Join(p.Output.Subtitles, ","))
+}
+
+// timingFormats は output.timing のタイミングのファイルの形式を返します。
+func (p *Project) timingFormats() ([]TimingFormat, error) {
+	return ParseTimingFormats(strings.Join(p.Output.Timing, ","))
+}
+
+// ConcatOptions は結合と後処理の設定を返します。
+func (p *Project) ConcatOptions() (ConcatOptions, error) {
+	post := p.Post
+	curve, err := wav.ParseFadeCurve(post.CrossfadeCurve)
+	if err != nil {
+		return ConcatOptions{}, err
+	}
+	mode, err := wav.ParseNormalizeMode(post.Normalize.Mode)
+	if err != nil {
+		return ConcatOptions{}, err
+	}
+	subtitles, err := p.subtitleFormats()
+	if err != nil {
+		return ConcatOptions{}, err
+	}
+	timing, err := p.timingFormats()
+	if err != nil {
+		return ConcatOptions{}, err
+	}
+	normalize := wav.DefaultNormalizeOptions(mode)
+	if v := post.Normalize.Peak; v != nil {
+		normalize.Peak = *v
+	}
+	if v := post.Normalize.Loudness; v != nil {
+		normalize.Loudness = *v
+	}
+	if v := post.Normalize.TruePeak; v != nil {
+		normalize.TruePeak = *v
+	}
+
+	opts := ConcatOptions{
+		Convert:         true,
+		Gap:             post.Gap,
+		PunctuationGaps: map[string]time.Duration{},
+		Crossfade:       post.Crossfade,
+		CrossfadeCurve:  curve,
+		Normalize:       normalize,
+		Subtitles:       subtitles,
+		Timing:          timing,
+	}
+	if post.SentenceGap > 0 {
+		opts.PunctuationGaps["。"] = post.SentenceGap
+	}
+	if post.CommaGap > 0 {
+		opts.PunctuationGaps["、"] = post.CommaGap
+	}
+	return opts, nil
+}
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"voicevox/wav"
+)
+
+func writeProject(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "job.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProject(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt", "c.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("テキスト"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := writeProject(t, dir, `
+inputs:
+  - "*.txt"
+  - c.md
+  - a.txt
+script:
+  length: 30
+  markdown:
+    heading_pause: 2s
+    code_block: コードは省略します
+  aozora:
+    page_pause: 3s
+  normalize:
+    symbols:
+      "&": と
+speakers:
+  default: ずんだもん
+  speakers:
+    ずんだもん: 3
+silence:
+  sentence: 250ms
+output:
+  name: "{project}_{index}_{name}"
+  subtitles: [srt, vtt]
+  timing: [csv]
+post:
+  sentence_gap: 200ms
+  crossfade: 10ms
+  crossfade_curve: equal-power
+  normalize:
+    mode: loudness
+    lufs: -16
+`)
+	p, err := LoadProject(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Name != "job" || p.Script.Length != 30 || p.Speaker != DefaultSpeaker || p.Output.Workers != DefaultWorkers {
+		t.Errorf("project = %+v", p)
+	}
+	if got := p.SilenceOptions(); got.Sentence != 250*time.Millisecond || got.Paragraph != DefaultParagraphPause || got.Format != EngineFormat {
+		t.Errorf("silence = %+v", got)
+	}
+
+	inputs, err := p.InputFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.md")}
+	if !reflect.DeepEqual(inputs, want) {
+		t.Errorf("inputs = %v, want %v", inputs, want)
+	}
+	if got := p.OutputName(inputs[1], 1); got != "job_2_b" {
+		t.Errorf("output name = %q", got)
+	}
+	if got := p.Path(p.Output.Dir); got != filepath.Join(dir, "out") {
+		t.Errorf("output dir = %q", got)
+	}
+
+	speakers, err := p.SpeakerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if speakers == nil || speakers.Default != "ずんだもん" {
+		t.Errorf("speakers = %+v", speakers)
+	}
+
+	if md := p.MarkdownOptions(); md.HeadingPause != 2*time.Second || md.CodeBlock != "コードは省略します" {
+		t.Errorf("markdown = %+v", md)
+	}
+
+	if in := p.InputOptions(); in.Format != FormatAuto || in.Aozora.PagePause != 3*time.Second || in.Aozora.HeadingPause != DefaultHeadingPause || in.Markdown.HeadingPause != 2*time.Second {
+		t.Errorf("input = %+v", in)
+	}
+	if n := p.TextNormalizer(); n == nil || n.Normalize("A&B 100%") != "AとB 百パーセント" {
+		t.Errorf("normalize = %+v", n)
+	}
+
+	opts, err := p.ConcatOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.PunctuationGaps["。"] != 200*time.Millisecond || opts.Crossfade != 10*time.Millisecond || opts.CrossfadeCurve != wav.FadeEqualPower {
+		t.Errorf("concat options = %+v", opts)
+	}
+	wantNorm := wav.NormalizeOptions{Mode: wav.NormalizeLoudness, Peak: wav.DefaultPeakTarget, Loudness: -16, TruePeak: wav.DefaultTruePeakLimit}
+	if opts.Normalize != wantNorm {
+		t.Errorf("normalize = %+v, want %+v", opts.Normalize, wantNorm)
+	}
+	if want := []SubtitleFormat{SubtitleSRT, SubtitleVTT}; !slices.Equal(opts.Subtitles, want) {
+		t.Errorf("subtitles = %v, want %v", opts.Subtitles, want)
+	}
+	if want := []TimingFormat{TimingCSV}; !slices.Equal(opts.Timing, want) {
+		t.Errorf("timing = %v, want %v", opts.Timing, want)
+	}
+}
+
+func TestLoadProjectErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "入力なし", content: "script:\n  length: 30\n", want: "inputs"},
+		{name: "不明な項目", content: "inputs: [a.txt]\nlenght: 30\n", want: "lenght"},
+		{name: "文字コード", content: "inputs: [a.txt]\nscript:\n  encoding: utf-16\n", want: "script.encoding"},
+		{name: "入力の形式", content: "inputs: [a.txt]\nscript:\n  format: html\n", want: "script.format"},
+		{name: "話者の指定が重複", content: "inputs: [a.txt]\nspeakers_file: s.yaml\nspeakers:\n  speakers:\n    a: 1\n", want: "同時に指定できません"},
+		{name: "defaultの話者がない", content: "inputs: [a.txt]\nspeakers:\n  default: b\n  speakers:\n    a: 1\n", want: "default"},
+		{name: "正規化の方法が不正", content: "inputs: [a.txt]\npost:\n  normalize:\n    mode: rms\n", want: "rms"},
+		{name: "字幕の形式が不正", content: "inputs: [a.txt]\noutput:\n  subtitles: [ass]\n", want: "output.subtitles"},
+		{name: "時間が不正", content: "inputs: [a.txt]\nsilence:\n  sentence: 0.4\n", want: "time.Duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadProject(writeProject(t, t.TempDir(), tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadProjectJoinCollision(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"all.txt", "part.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("本文。"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "入力の出力と同じ名前", content: "inputs: ['*.txt']\noutput:\n  join: all\n", wantErr: true},
+		{name: "名前の書式で同じになる", content: "inputs: ['*.txt']\noutput:\n  name: 'q_{name}'\n  join: q_part\n", wantErr: true},
+		{name: "異なる名前", content: "inputs: ['*.txt']\noutput:\n  join: joined\n", wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadProject(writeProject(t, dir, tt.content))
+			if (err != nil) != tt.wantErr || err != nil && !strings.Contains(err.Error(), "output.join") {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProjectInputFilesNoMatch(t *testing.T) {
+	p, err := LoadProject(writeProject(t, t.TempDir(), "inputs: [in/*.txt]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.InputFiles(); err == nil {
+		t.Error("一致するファイルがない場合はエラーになるはず")
+	}
+}
+
+func TestExampleProject(t *testing.T) {
+	p, err := LoadProject(filepath.Join("..", "projects", "g3_202401_q", "project.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.SpeakerConfig(); err != nil {
+		t.Error(err)
+	}
+	if d, err := p.LoadDictionary(); err != nil || d == nil {
+		t.Errorf("LoadDictionary() = %v, %v", d, err)
+	}
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// 動画の既定の設定
+const (
+	DefaultVideoWidth      = 1920
+	DefaultVideoHeight     = 1080
+	DefaultVideoFrameRate  = 30
+	DefaultCharacterHeight = 0.6
+)
+
+// RenderOptions は静止画とナレーションの音声から ffmpeg で動画(MP4)を作る設定です。
+type RenderOptions struct {
+	// FFmpeg は ffmpeg の実行ファイルです。空の場合は PATH から "ffmpeg" を探します。
+	FFmpeg string
+	// Background は背景画像です。縦横比を保って動画の大きさに収め、余白は黒で埋めます。
+	Background string
+	// Character は背景の下端に重ねる立ち絵の画像です。空の場合は重ねません。
+	Character string
+	// CharacterHeight は立ち絵の高さの動画の高さに対する割合です。0 の場合は DefaultCharacterHeight です。
+	CharacterHeight float64
+	// CharacterLeft が true の場合は立ち絵を左下に、false の場合は右下に置きます。
+	CharacterLeft bool
+	// Audio はナレーションの音声ファイルです。動画の長さは音声に合わせます。
+	Audio string
+	// Subtitles は動画に焼き込む字幕ファイル(SRT, WebVTT)です。空の場合は字幕を入れません。
+	Subtitles string
+	// Output は出力する動画ファイルです。
+	Output string
+
+	// Width, Height, FrameRate は動画の大きさとフレームレートです。0 の場合は既定値を使います。
+	Width     int
+	Height    int
+	FrameRate int
+}
+
+func (o RenderOptions) validate() error {
+	if o.Background == "" {
+		return errors.New("背景画像を指定してください")
+	}
+	if o.Audio == "" {
+		return errors.New("音声ファイルを指定してください")
+	}
+	if o.Output == "" {
+		return errors.New("出力ファイルを指定してください")
+	}
+	if o.CharacterHeight < 0 || o.CharacterHeight > 1 {
+		return fmt.Errorf("立ち絵の高さの割合は0から1の間で指定してください: %g", o.CharacterHeight)
+	}
+	return nil
+}
+
+// Args は ffmpeg に渡す引数(実行ファイルを除く)を返します。出力先は Output です。
+// Render は出力先を一時ファイルにした argsTo の引数で実行します。
+func (o RenderOptions) Args() ([]string, error) {
+	return o.argsTo(o.Output)
+}
+
+// argsTo は output に書き出す ffmpeg の引数を返します。
+func (o RenderOptions) argsTo(output string) ([]string, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	width, height, rate := orDefault(o.Width, DefaultVideoWidth), orDefault(o.Height, DefaultVideoHeight), orDefault(o.FrameRate, DefaultVideoFrameRate)
+
+	args := []string{"-y", "-loop", "1", "-framerate", fmt.Sprint(rate), "-i", o.Background}
+	graph := []string{fmt.Sprintf("[0:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1[bg]", width, height, width, height)}
+	video := "bg"
+	audio := 1
+	if o.Character != "" {
+		args = append(args, "-loop", "1", "-framerate", fmt.Sprint(rate), "-i", o.Character)
+		audio = 2
+		ratio := o.CharacterHeight
+		if ratio == 0 {
+			ratio = DefaultCharacterHeight
+		}
+		x := "W-w"
+		if o.CharacterLeft {
+			x = "0"
+		}
+		// 偶数の高さにしておく(-2 は縦横比を保った偶数の幅)
+		graph = append(graph,
+			fmt.Sprintf("[1:v]scale=-2:%d[ch]", int(float64(height)*ratio)/2*2),
+			fmt.Sprintf("[%s][ch]overlay=%s:H-h:format=auto[ov]", video, x))
+		video = "ov"
+	}
+	if o.Subtitles != "" {
+		graph = append(graph, fmt.Sprintf("[%s]subtitles=filename=%s[sub]", video, escapeFilterValue(o.Subtitles)))
+		video = "sub"
+	}
+	graph = append(graph, fmt.Sprintf("[%s]format=yuv420p[v]", video))
+
+	args = append(args,
+		"-i", o.Audio,
+		"-filter_complex", strings.Join(graph, ";"),
+		"-map", "[v]", "-map", fmt.Sprintf("%d:a", audio),
+		"-c:v", "libx264", "-tune", "stillimage", "-r", fmt.Sprint(rate),
+		"-c:a", "aac", "-b:a", "192k",
+		"-shortest", "-movflags", "+faststart",
+		output)
+	return args, nil
+}
+
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// escapeFilterValue は s をフィルターのオプションの値として使えるよう、
+// オプションの値とフィルターグラフの2段階でエスケープします。
+func escapeFilterValue(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(s)
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(s)
+}
+
+// ffmpeg は実行する ffmpeg のパスです。
+func (o RenderOptions) ffmpeg() string {
+	if o.FFmpeg != "" {
+		return o.FFmpeg
+	}
+	return "ffmpeg"
+}
+
+// Command は実行する ffmpeg のコマンドラインをシェルに貼り付けられる形で返します。
+// 出力先は Output です。Render は同じ引数で Output と同じディレクトリの一時ファイルに書き出し、
+// 成功した後で Output に名前を変えます。
+func (o RenderOptions) Command() (string, error) {
+	args, err := o.Args()
+	if err != nil {
+		return "", err
+	}
+	quoted := []string{shellQuote(o.ffmpeg())}
+	for _, a := range args {
+		quoted = append(quoted, shellQuote(a))
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+// shellQuote は s を必要な場合にシングルクォートで囲みます。
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Render は ffmpeg を実行して動画を作ります。ffmpeg の出力は標準エラー出力に流します。
+// 動画は出力先と同じディレクトリの一時ファイルに書き出してから名前を変えるため、
+// 失敗した場合や ctx がキャンセルされた場合も、前回の実行で作った動画はそのまま残ります。
+func Render(ctx context.Context, o RenderOptions) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	inputs := []string{o.Background, o.Character, o.Audio, o.Subtitles}
+	for _, path := range inputs {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("入力ファイルがありません: %w", err)
+		}
+	}
+	ffmpeg, err := exec.LookPath(o.ffmpeg())
+	if err != nil {
+		return fmt.Errorf("ffmpeg が見つかりません。インストールするか実行ファイルを指定してください: %w", err)
+	}
+
+	// ffmpeg は拡張子で形式を決めるため、一時ファイルにも同じ拡張子を付ける
+	ext := filepath.Ext(o.Output)
+	tmp, err := os.CreateTemp(filepath.Dir(o.Output), "."+strings.TrimSuffix(filepath.Base(o.Output), ext)+".*"+ext)
+	if err != nil {
+		return fmt.Errorf("一時ファイルの作成に失敗しました: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name()) // 名前を変えた後は何もしない
+
+	args, err := o.argsTo(tmp.Name())
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, ffmpeg, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("ffmpeg の実行に失敗しました: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), o.Output); err != nil {
+		return fmt.Errorf("動画の保存に失敗しました: %w", err)
+	}
+	return nil
+}
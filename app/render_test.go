@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRenderArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RenderOptions
+		// want は引数に含まれるはずの値、graph はフィルターグラフに含まれるはずの値です
+		want  []string
+		graph []string
+	}{
+		{
+			name:  "背景と音声のみ",
+			opts:  RenderOptions{Background: "bg.png", Audio: "out/all.wav", Output: "out/all.mp4"},
+			want:  []string{"-i", "bg.png", "out/all.wav", "1:a", "out/all.mp4"},
+			graph: []string{"scale=1920:1080:force_original_aspect_ratio=decrease", "[bg]format=yuv420p[v]"},
+		},
+		{
+			name: "立ち絵と字幕",
+			opts: RenderOptions{
+				Background: "bg.png", Character: "zundamon.png", CharacterHeight: 0.5, CharacterLeft: true,
+				Audio: "all.wav", Subtitles: "all.srt", Output: "all.mp4", Width: 1280, Height: 720,
+			},
+			want:  []string{"zundamon.png", "2:a"},
+			graph: []string{"[1:v]scale=-2:360[ch]", "overlay=0:H-h", "[ov]subtitles=filename=all.srt[sub]", "[sub]format=yuv420p[v]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := tt.opts.Args()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !slices.Contains(args, w) {
+					t.Errorf("%q が含まれていない: %q", w, args)
+				}
+			}
+			i := slices.Index(args, "-filter_complex")
+			if i < 0 {
+				t.Fatalf("-filter_complex がない: %q", args)
+			}
+			for _, g := range tt.graph {
+				if !strings.Contains(args[i+1], g) {
+					t.Errorf("%q が含まれていない: %s", g, args[i+1])
+				}
+			}
+			if args[len(args)-1] != tt.opts.Output {
+				t.Errorf("最後の引数 = %q, want %q", args[len(args)-1], tt.opts.Output)
+			}
+		})
+	}
+
+	if _, err := (RenderOptions{Audio: "a.wav", Output: "a.mp4"}).Args(); err == nil {
+		t.Error("背景画像がない場合はエラーになるはず")
+	}
+}
+
+func TestEscapeFilterValue(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"out/all.srt", "out/all.srt"},
+		{`C:\subs\a.srt`, `C\\:\\\\subs\\\\a.srt`},
+		{"it's[1].srt", `it\\\'s\[1\].srt`},
+	}
+	for _, tt := range tests {
+		if got := escapeFilterValue(tt.in); got != tt.want {
+			t.Errorf("escapeFilterValue(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderCommand(t *testing.T) {
+	o := RenderOptions{Background: "背景 画像.png", Audio: "a.wav", Output: "a.mp4"}
+	cmd, err := o.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(cmd, "ffmpeg -y ") || !strings.Contains(cmd, "-i '背景 画像.png'") || !strings.HasSuffix(cmd, " a.mp4") {
+		t.Errorf("command = %s", cmd)
+	}
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Errorf("shellQuote = %s", got)
+	}
+}
+
+func TestRenderKeepsOutputOnFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("シェルスクリプトの ffmpeg の代わりを実行できない")
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"bg.png", "a.wav"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := filepath.Join(dir, "out.mp4")
+	if err := os.WriteFile(output, []byte("previous\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		script  string
+		wantErr bool
+		want    string
+	}{
+		// 最後の引数が出力ファイル
+		{name: "失敗", script: `for a; do out=$a; done; echo partial > "$out"; exit 1`, wantErr: true, want: "previous\n"},
+		{name: "成功", script: `for a; do out=$a; done; echo rendered > "$out"`, want: "rendered\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ffmpeg := filepath.Join(dir, "ffmpeg.sh")
+			if err := os.WriteFile(ffmpeg, []byte("#!/bin/sh\n"+tt.script+"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			o := RenderOptions{FFmpeg: ffmpeg, Background: filepath.Join(dir, "bg.png"), Audio: filepath.Join(dir, "a.wav"), Output: output}
+			err := Render(context.Background(), o)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("output = %q, want %q", data, tt.want)
+			}
+			// 一時ファイルは残さない
+			if tmp, _ := filepath.Glob(filepath.Join(dir, ".out.*")); len(tmp) > 0 {
+				t.Errorf("一時ファイルが残っている: %v", tmp)
+			}
+		})
+	}
+}
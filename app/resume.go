@@ -0,0 +1,100 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+	"voicevox/wav"
+)
+
+// linesFile は行ごとの音声のディレクトリに保存する、各行の内容の記録です。
+const linesFile = "lines.json"
+
+// signature は line の音声の内容を決める値から計算した値です。
+// 音声を合成する行はエンジンに送るテキスト、話者、韻律、エンジンのバージョンとユーザー辞書、
+// 無音行は無音の長さと形式を使います。
+func (s *Synthesizer) signature(line ScriptLine) string {
+	var v struct {
+		Text       string `json:",omitempty"`
+		SpeakerID  int    `json:",omitempty"`
+		Prosody    Prosody
+		Engine     string        `json:",omitempty"`
+		Dictionary string        `json:",omitempty"`
+		Pause      time.Duration `json:",omitempty"`
+		Format     *wav.Format   `json:",omitempty"`
+	}
+	if line.Text == "" {
+		v.Pause, v.Format = s.Silence.Duration(line), &s.Silence.Format
+	} else {
+		v.Text, v.SpeakerID, v.Prosody = s.text(line), line.SpeakerID, line.Prosody
+		v.Engine, v.Dictionary = s.EngineVersion, s.DictionaryHash
+	}
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// PrepareLineDir は台本の行の音声を保存するディレクトリ dir を準備し、音声合成が必要な行の番号(0から)を返します。
+// signatures[i] は i 行目の音声の内容を決める値(Synthesizer.signature)、path(i) は i 行目の音声ファイルのパスです。
+//
+// resume が false の場合は dir 内のファイルを全て削除し、全ての行を返します。
+// resume が true の場合は前回の実行の記録と比べ、内容が変わっていない行の音声ファイルが
+// 完全な形で残っていればその行を除きます。
+func PrepareLineDir(dir string, signatures []string, path func(i int) string, resume bool) ([]int, error) {
+	var prev []string
+	if resume {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("ディレクトリの作成に失敗しました: %w", err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, linesFile))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("前回の実行の記録の読み込みに失敗しました: %w", err)
+		default:
+			if err := json.Unmarshal(data, &prev); err != nil {
+				return nil, fmt.Errorf("前回の実行の記録の解析に失敗しました: %s: %w", dir, err)
+			}
+		}
+	} else if err := CreateDirAndRemoveFiles(dir); err != nil {
+		return nil, err
+	}
+
+	var todo []int
+	for i := range signatures {
+		if i < len(prev) && prev[i] == signatures[i] && isCompleteWAV(path(i)) {
+			continue
+		}
+		// 内容の変わった行の古い音声は、音声合成に失敗した場合に使われないよう削除しておく
+		if err := os.Remove(path(i)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		todo = append(todo, i)
+	}
+
+	data, err := json.Marshal(signatures)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, linesFile), data); err != nil {
+		return nil, fmt.Errorf("実行の記録の保存に失敗しました: %w", err)
+	}
+	return todo, nil
+}
+
+// isCompleteWAV は path が読み込めるWAVファイルであるかを返します。
+func isCompleteWAV(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	_, err = wav.Decode(f)
+	return err == nil
+}
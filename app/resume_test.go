@@ -0,0 +1,102 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"voicevox/wav"
+)
+
+func TestPrepareLineDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "lines")
+	path := func(i int) string { return filepath.Join(dir, fmt.Sprintf("%05d.wav", i)) }
+	script, err := ParseScript([]string{"一行目。", "", "三行目。", "四行目。"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	synth := NewSynthesizer(nil)
+	signatures := func() []string {
+		var s []string
+		for _, l := range script {
+			s = append(s, synth.signature(l))
+		}
+		return s
+	}
+
+	todo, err := PrepareLineDir(dir, signatures(), path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(todo, want) {
+		t.Fatalf("todo = %v, want %v", todo, want)
+	}
+
+	// 0, 1, 3 行目まで保存したところで中断し、3 行目は書きかけで残った
+	silence := wav.Silence(EngineFormat, 10*time.Millisecond)
+	for _, i := range []int{0, 1} {
+		if err := wav.WriteFile(path(i), silence); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path(3), []byte("RIFF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 行目を書き換えて再開する
+	script[0].Text = "書き換えた一行目。"
+	todo, err = PrepareLineDir(dir, signatures(), path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 2, 3}; !reflect.DeepEqual(todo, want) {
+		t.Errorf("resume: todo = %v, want %v", todo, want)
+	}
+	if _, err := os.Stat(path(1)); err != nil {
+		t.Errorf("再開時は保存済みのファイルを残すはず: %v", err)
+	}
+
+	// 再開しない場合は全て削除して最初から
+	todo, err = PrepareLineDir(dir, signatures(), path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todo) != 4 {
+		t.Errorf("todo = %v", todo)
+	}
+	if _, err := os.Stat(path(1)); !os.IsNotExist(err) {
+		t.Errorf("保存済みのファイルは削除されるはず: %v", err)
+	}
+}
+
+func TestSynthesizerSignature(t *testing.T) {
+	line, err := ParseScriptLine(1, "｜吉岡《よしおか》さんは2人。")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := NewSynthesizer(nil)
+	tests := []struct {
+		name    string
+		modify  func(s *Synthesizer, l *ScriptLine)
+		changed bool
+	}{
+		{name: "変更なし", modify: func(s *Synthesizer, l *ScriptLine) {}, changed: false},
+		{name: "表示の本文だけの変更", modify: func(s *Synthesizer, l *ScriptLine) { l.Text = "吉岡さんは二人。" }, changed: false},
+		{name: "読み方の変更", modify: func(s *Synthesizer, l *ScriptLine) { l.Reading = "よしおかさんは3人。" }, changed: true},
+		{name: "テキストの変換の変更", modify: func(s *Synthesizer, l *ScriptLine) { s.Normalizer = nil }, changed: true},
+		{name: "話者の変更", modify: func(s *Synthesizer, l *ScriptLine) { l.SpeakerID = 3 }, changed: true},
+		{name: "エンジンのバージョンの変更", modify: func(s *Synthesizer, l *ScriptLine) { s.EngineVersion = "0.15.0" }, changed: true},
+		{name: "ユーザー辞書の変更", modify: func(s *Synthesizer, l *ScriptLine) { s.DictionaryHash = "changed" }, changed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, l := *base, line
+			tt.modify(&s, &l)
+			if got := s.signature(l) != base.signature(line); got != tt.changed {
+				t.Errorf("changed = %v, want %v", got, tt.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RetryPolicy は一時的な失敗(エンジンに接続できない、5xx、429、タイムアウト)を再試行する設定です。
+// 待ち時間は InitialDelay から始めて Multiplier 倍ずつ、MaxDelay まで伸ばします。
+type RetryPolicy struct {
+	// MaxAttempts は最初の1回を含めた最大の試行回数です。1以下の場合は再試行しません。
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+// DefaultRetryPolicy は既定の再試行の設定を返します。
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  4,
+		InitialDelay: 500 * time.Millisecond,
+		MaxDelay:     10 * time.Second,
+		Multiplier:   2,
+	}
+}
+
+// delay は attempt 回目(1から)の失敗の後に待つ時間です。
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := float64(p.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		d *= max(p.Multiplier, 1)
+		if p.MaxDelay > 0 && d >= float64(p.MaxDelay) {
+			return p.MaxDelay
+		}
+	}
+	return time.Duration(d)
+}
+
+// Do は f が成功するか、一時的でないエラーを返すか、試行回数を使い切るまで f を繰り返します。
+// 待っている間に ctx がキャンセルされた場合は ctx のエラーを返します。
+func (p RetryPolicy) Do(ctx context.Context, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			if attempt > 1 {
+				return fmt.Errorf("%d回試行しましたが失敗しました: %w", attempt, err)
+			}
+			return err
+		}
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// IsTransient は err が再試行すれば成功する可能性のある失敗かどうかを返します。
+// エンジンに接続できない、サーバーエラー(5xx)、リクエストが多すぎる(429)、タイムアウトが該当します。
+// 話者が存在しない、入力の検証エラー、キャンセルは再試行しません。
+func IsTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrEngineUnreachable) || errors.Is(err, ErrEngineTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var serr *StatusError
+	if errors.As(err, &serr) {
+		return serr.StatusCode >= 500 || serr.StatusCode == http.StatusTooManyRequests
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+// LineError は台本の1行の音声合成の失敗です。
+type LineError struct {
+	// Index は台本の中の位置(0から)です。
+	Index int
+	Line  ScriptLine
+	Err   error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("%d行目「%s」: %v", e.Line.Number, excerpt(e.Line.Text, 20), e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// SynthesisError は音声合成に失敗した全ての行の報告です。
+type SynthesisError struct {
+	// Total は音声合成しようとした行数です。
+	Total  int
+	Failed []*LineError
+}
+
+func (e *SynthesisError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d行中%d行の音声合成に失敗しました:", e.Total, len(e.Failed))
+	for _, f := range e.Failed {
+		b.WriteString("\n  ")
+		b.WriteString(f.Error())
+	}
+	return b.String()
+}
+
+func (e *SynthesisError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f
+	}
+	return errs
+}
+
+// excerpt は s が n 文字を超える場合に先頭 n 文字に切り詰めます。
+func excerpt(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := p.delay(i + 1); got != w {
+			t.Errorf("delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: fmt.Errorf("%w (http://localhost): refused", ErrEngineUnreachable), want: true},
+		{err: &StatusError{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{err: &StatusError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), want: true},
+		{err: fmt.Errorf("%w (http://localhost, 1s): slow", ErrEngineTimeout), want: true},
+		{err: &StatusError{StatusCode: http.StatusBadRequest}, want: false},
+		{err: &ValidationError{}, want: false},
+		{err: ErrUnknownSpeaker, want: false},
+		{err: context.Canceled, want: false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSynthesizeRetry(t *testing.T) {
+	engine := newFakeEngine(t)
+	synth := NewSynthesizer(engine.client(t))
+	synth.Retry = RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 2}
+	line := ScriptLine{Number: 7, Text: "こんにちは。", SpeakerID: 1}
+	ctx := context.Background()
+
+	// 2回失敗した後に成功する
+	engine.failStatus = http.StatusServiceUnavailable
+	engine.failures.Store(2)
+	if _, err := synth.Synthesize(ctx, line); err != nil {
+		t.Fatalf("再試行で成功するはず: %v", err)
+	}
+
+	// 試行回数を使い切ると最後のエラーを返す
+	engine.failures.Store(3)
+	_, err := synth.Synthesize(ctx, line)
+	var serr *StatusError
+	if !errors.As(err, &serr) || serr.StatusCode != http.StatusServiceUnavailable || !strings.Contains(err.Error(), "3回") {
+		t.Errorf("err = %v", err)
+	}
+
+	// 一時的でない失敗は再試行しない
+	engine.failStatus = http.StatusBadRequest
+	engine.failures.Store(1)
+	if _, err := synth.Synthesize(ctx, line); err == nil {
+		t.Error("expected error")
+	}
+	if engine.failures.Load() != 0 {
+		t.Errorf("400 は再試行しないはず (残り %d)", engine.failures.Load())
+	}
+
+	// 待っている間にキャンセルされた場合は中断する
+	synth.Retry.InitialDelay = time.Hour
+	engine.failStatus = http.StatusServiceUnavailable
+	engine.failures.Store(1)
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := synth.Synthesize(ctx, line); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSynthesisError(t *testing.T) {
+	err := &SynthesisError{Total: 10, Failed: []*LineError{
+		{Index: 2, Line: ScriptLine{Number: 3, Text: "とても長い行の本文はエラーの報告では途中で切り詰めて表示します。"}, Err: ErrUnknownSpeaker},
+		{Index: 5, Line: ScriptLine{Number: 6, Text: "短い行"}, Err: &StatusError{StatusCode: 500}},
+	}}
+	msg := err.Error()
+	for _, want := range []string{"10行中2行", "3行目「とても長い行の本文はエラーの報告では途中…」", "6行目「短い行」"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message %q does not contain %q", msg, want)
+		}
+	}
+	if !errors.Is(err, ErrUnknownSpeaker) {
+		t.Error("errors.Is で各行のエラーを取り出せるはず")
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// reRuby はテキスト中のルビ(読み方の指定)の記法です。
+//
+//   - 青空文庫形式: ｜吉岡《よしおか》(｜は半角の | でも構いません)
+//   - 青空文庫形式で｜を省略したもの: 吉岡《よしおか》(《の直前に続く漢字にルビを振ります)
+//   - 波括弧形式: {吉岡|よしおか}
+var reRuby = regexp.MustCompile(`[｜|]([^｜|《》\n]+)《([^《》\n]+)》|([々〆〇ヶ\p{Han}]+)《([^《》\n]+)》|\{([^{}|\n]+)\|([^{}|\n]+)\}`)
+
+// ParseRuby は text のルビの記法を取り除き、表示する本文 display と、ルビを振った語を読み方に置き換えた reading を返します。
+// ルビがない場合、reading は空文字列です。
+func ParseRuby(text string) (display, reading string) {
+	matches := reRuby.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text, ""
+	}
+	var d, r strings.Builder
+	prev := 0
+	for _, m := range matches {
+		d.WriteString(text[prev:m[0]])
+		r.WriteString(text[prev:m[0]])
+		// 一致した形式の (表記, 読み方) のグループ
+		for g := 2; g < len(m); g += 4 {
+			if m[g] >= 0 {
+				d.WriteString(text[m[g]:m[g+1]])
+				r.WriteString(text[m[g+2]:m[g+3]])
+				break
+			}
+		}
+		prev = m[1]
+	}
+	d.WriteString(text[prev:])
+	r.WriteString(text[prev:])
+	return d.String(), r.String()
+}
+
+// rubyLength はルビの記法を除いた、表示される文字数を返します。
+func rubyLength(s string) int {
+	display, _ := ParseRuby(s)
+	return utf8.RuneCountInString(display)
+}
+
+// joinRubyWords は形態素解析で分かれたルビの記法を1語にまとめます。
+// 台本の行の途中でルビの記法が分かれないようにするためです。
+// reRuby に一致する範囲の語だけをまとめ、対応する記法のない ｜ や { はそのままにします。
+func joinRubyWords(words []string) []string {
+	matches := reRuby.FindAllStringIndex(strings.Join(words, ""), -1)
+	var out []string
+	pos := 0
+	for _, w := range words {
+		start := pos
+		pos += len(w)
+		// ルビの記法の途中から始まる語は前の語につなげる
+		inRuby := slices.ContainsFunc(matches, func(m []int) bool { return m[0] < start && start < m[1] })
+		if inRuby && len(out) > 0 {
+			out[len(out)-1] += w
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
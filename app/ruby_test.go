@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseRuby(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantDisplay string
+		wantReading string
+	}{
+		{"ルビなし", "吉岡さんです。", "吉岡さんです。", ""},
+		{"青空文庫形式", "｜吉岡《よしおか》はるかです。", "吉岡はるかです。", "よしおかはるかです。"},
+		{"半角の縦線", "|吉岡《よしおか》です。", "吉岡です。", "よしおかです。"},
+		{"縦線の省略", "作家の吉岡《よしおか》です。", "作家の吉岡です。", "作家のよしおかです。"},
+		{"縦線の省略と仮名", "それは吉岡《よしおか》", "それは吉岡", "それはよしおか"},
+		{"波括弧形式", "{吉岡|よしおか}{遥|はるか}さん", "吉岡遥さん", "よしおかはるかさん"},
+		{"仮名の表記", "｜ＦＰ《エフピー》三級", "ＦＰ三級", "エフピー三級"},
+		{"閉じていない", "{吉岡|よしおか", "{吉岡|よしおか", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			display, reading := ParseRuby(tt.in)
+			if display != tt.wantDisplay || reading != tt.wantReading {
+				t.Errorf("ParseRuby(%q) = %q, %q, want %q, %q", tt.in, display, reading, tt.wantDisplay, tt.wantReading)
+			}
+		})
+	}
+}
+
+func TestSegmentTextRuby(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"波括弧形式", "今日は作家の{吉岡|よしおか}{遥|はるか}さんが書いた新しい本を順番に紹介します。"},
+		{"青空文庫形式", "今日は作家の吉岡《よしおか》遥《はるか》さんが書いた新しい本を順番に紹介します。"},
+		{"縦線", "今日は作家の｜吉岡遥《よしおかはるか》さんが書いた新しい本を順番に紹介します。"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := 8
+			segments, err := SegmentText(tt.text, &l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(segments) < 2 {
+				t.Fatalf("分割されていません: %q", segments)
+			}
+			// 各行のルビの記法が閉じていれば、記法を除いた本文に記号は残らない
+			var joined string
+			for _, seg := range segments {
+				display, _ := ParseRuby(seg.Text)
+				if strings.ContainsAny(display, "{}|｜《》") {
+					t.Errorf("ルビの記法が行の途中で分かれています: %q", segments)
+				}
+				joined += display
+			}
+			if want, _ := ParseRuby(tt.text); joined != want {
+				t.Errorf("本文 = %q, want %q", joined, want)
+			}
+		})
+	}
+}
+
+func TestJoinRubyWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{"波括弧形式", []string{"作家", "の", "{", "吉岡", "|", "よし", "おか", "}", "さん"}, []string{"作家", "の", "{吉岡|よしおか}", "さん"}},
+		{"縦線のない青空文庫形式", []string{"作家", "の", "吉", "岡", "《", "よしおか", "》", "さん"}, []string{"作家", "の", "吉岡《よしおか》", "さん"}},
+		{"対応する記法のない波括弧", []string{"{", "注意", "を", "読む", "。"}, []string{"{", "注意", "を", "読む", "。"}},
+		{"対応する記法のない縦線", []string{"A", "｜", "B", "を", "選ぶ", "。"}, []string{"A", "｜", "B", "を", "選ぶ", "。"}},
+		{"記法のない括弧の後のルビ", []string{"{", "注意", "の", "｜", "吉岡", "《", "よしおか", "》", "さん"}, []string{"{", "注意", "の", "｜吉岡《よしおか》", "さん"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinRubyWords(tt.words); !slices.Equal(got, tt.want) {
+				t.Errorf("joinRubyWords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSynthesizeRuby(t *testing.T) {
+	engine := newFakeEngine(t)
+	synth := NewSynthesizer(engine.client(t))
+	lines, err := ParseScript([]string{"めたん: ｜吉岡《よしおか》さんは2人。"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (*SpeakerConfig)(nil).Assign(lines, 1); err != nil {
+		t.Fatal(err)
+	}
+	if want := "めたん: 吉岡さんは2人。"; lines[0].Text != want {
+		t.Errorf("Text = %q, want %q", lines[0].Text, want)
+	}
+	_, query, err := synth.SynthesizeQuery(context.Background(), lines[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 読み方を数字の変換の後にエンジンに送る
+	if want := "めたん: よしおかさんは二人。"; query.Kana != want {
+		t.Errorf("エンジンに送ったテキスト = %q, want %q", query.Kana, want)
+	}
+}
@@ -2,9 +2,7 @@ package app
 
 import (
 	"fmt"
-	"os"
 	"strings"
-	"unicode/utf8"
 
 	"github.com/ikawaha/kagome-dict/ipa"
 	"github.com/ikawaha/kagome/v2/tokenizer"
@@ -51,33 +49,30 @@ func processAndFormatSegment(segmentText string, maxLength int, t *tokenizer.Tok
 	trimmedTextForTokenization := Trim(textForTokenization)
 
 	if trimmedTextForTokenization != "" {
-		tokens := t.Tokenize(trimmedTextForTokenization)
-		for i := 0; i < len(tokens); i++ {
-			token := tokens[i]
-			if token.Class == tokenizer.DUMMY {
-				continue
+		var words []string
+		for _, token := range t.Tokenize(trimmedTextForTokenization) {
+			if token.Class != tokenizer.DUMMY {
+				words = append(words, token.Surface)
 			}
-			word := token.Surface
-			currentLineRuneCount := utf8.RuneCountInString(currentLineBuilder.String())
-			prospectiveRuneCount := currentLineRuneCount + utf8.RuneCountInString(word)
+		}
+		// ルビの記法は1語として扱い、文字数は表示される文字だけを数える
+		words = joinRubyWords(words)
+		for i := 0; i < len(words); i++ {
+			word := words[i]
+			currentLineRuneCount := rubyLength(currentLineBuilder.String())
+			prospectiveRuneCount := currentLineRuneCount + rubyLength(word)
 
 			if currentLineBuilder.Len() > 0 && prospectiveRuneCount > maxLength {
 				remainingLength := 0
-				for j := i; j < len(tokens); j++ {
-					if tokens[j].Class == tokenizer.DUMMY {
-						continue
-					}
-					remainingLength += utf8.RuneCountInString(tokens[j].Surface)
+				for j := i; j < len(words); j++ {
+					remainingLength += rubyLength(words[j])
 				}
 
 				if remainingLength <= 10 {
-					for j := i; j < len(tokens); j++ {
-						if tokens[j].Class == tokenizer.DUMMY {
-							continue
-						}
-						currentLineBuilder.WriteString(tokens[j].Surface)
+					for j := i; j < len(words); j++ {
+						currentLineBuilder.WriteString(words[j])
 					}
-					i = len(tokens) - 1 // Consume all remaining tokens
+					i = len(words) - 1 // Consume all remaining tokens
 				} else {
 					bufferForThisSegment = append(bufferForThisSegment, currentLineBuilder.String())
 					currentLineBuilder.Reset()
@@ -122,24 +117,89 @@ func processAndFormatSegment(segmentText string, maxLength int, t *tokenizer.Tok
 	return formattedLines
 }
 
+// BreakKind は無音行の種類です。
+type BreakKind int
+
+const (
+	// BreakNone は無音行ではないことを表します。
+	BreakNone BreakKind = iota
+	// BreakSentence は句読点の連続などから生じた文の区切りの無音行です。
+	BreakSentence
+	// BreakParagraph は元のテキストの空行から生じた段落の区切りの無音行です。
+	BreakParagraph
+)
+
+// Segment は Create が生成する台本の1行です。
+type Segment struct {
+	// Text は行の本文です。空文字列は無音行を表します。
+	Text string
+	// Break は無音行の種類です。Text が空でない場合は BreakNone です。
+	Break BreakKind
+}
+
+// Create は path のテキストを台本の行に分割します。無音行は空文字列になります。
 func Create(path string, l *int) ([]string, error) {
+	segments, err := CreateSegments(path, l)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(segments))
+	for i, seg := range segments {
+		lines[i] = seg.Text
+	}
+	return lines, nil
+}
+
+// SegmentLines は segments を台本ファイルの行に変換します。
+// 文の区切りは「。」のみの行、段落の区切りは空行になり、ReadScriptFile で読み直すと同じ無音行になります。
+func SegmentLines(segments []Segment) []string {
+	lines := make([]string, len(segments))
+	for i, seg := range segments {
+		switch seg.Break {
+		case BreakSentence:
+			lines[i] = "。"
+		default:
+			lines[i] = seg.Text
+		}
+	}
+	return lines
+}
+
+// CreateSegments は Create と同様に path のテキストを台本の行に分割し、無音行の種類も返します。
+// Markdown ファイル(.md, .markdown)は DefaultMarkdownOptions で記法を取り除いてから分割します。
+func CreateSegments(path string, l *int) ([]Segment, error) {
+	return CreateSegmentsWith(path, l, DefaultInputOptions())
+}
+
+// CreateSegmentsMarkdown は CreateSegments と同様ですが、Markdown ファイルを md の設定で変換します。
+func CreateSegmentsMarkdown(path string, l *int, md MarkdownOptions) ([]Segment, error) {
+	opts := DefaultInputOptions()
+	opts.Markdown = md
+	return CreateSegmentsWith(path, l, opts)
+}
+
+// CreateSegmentsWith は CreateSegments と同様ですが、入力ファイルを opts の形式と設定で変換してから分割します。
+func CreateSegmentsWith(path string, l *int, opts InputOptions) ([]Segment, error) {
+	text, err := ReadInput(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return SegmentText(text, l)
+}
+
+// SegmentText はテキストを1行あたり最大 *l 文字を目安に台本の行に分割します。
+// ルビの記法(ParseRuby)は行の途中で分けずにそのまま残し、文字数は表示される文字だけを数えます。
+func SegmentText(entireText string, l *int) ([]Segment, error) {
 	if l == nil {
 		defaultLine := 20 // デフォルト値を20に変更（テストケースに合わせる）
 		l = &defaultLine
 	}
 	maxLength := *l
 
-	// ファイルの内容を読み込む
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file content: %w", err)
-	}
-	entireText := string(content)
 	// Windowsの改行コード CRLF を LF に統一しておく（テストの一貫性のため）
 	// Split前に実行することで、Split結果が \r を含まないようにする
 	entireText = strings.ReplaceAll(entireText, "\r\n", "\n")
 
-
 	// 形態素解析器の準備
 	t, err := tokenizer.New(ipa.Dict(), tokenizer.OmitBosEos())
 	if err != nil {
@@ -159,7 +219,14 @@ func Create(path string, l *int) ([]string, error) {
 			continue
 		}
 
-		currentProcessingLine := trimmedOriginalLine // この行の処理対象部分
+		// 行頭の韻律タグ([speed=1.2] など)は形態素解析の対象から外し、
+		// この行から生成された各行の先頭に付け直す
+		tag, currentProcessingLine := splitDirectiveTag(trimmedOriginalLine) // この行の処理対象部分
+		if tag != "" && currentProcessingLine == "" {
+			allFormattedLinesFromSegments = append(allFormattedLinesFromSegments, tag+"\n")
+			continue
+		}
+		firstLineOfThisLine := len(allFormattedLinesFromSegments)
 		for len(currentProcessingLine) > 0 {
 			idxJaKuten := -1
 			kutenLen := 0
@@ -201,18 +268,32 @@ func Create(path string, l *int) ([]string, error) {
 				allFormattedLinesFromSegments = append(allFormattedLinesFromSegments, processedSegmentLines...)
 			}
 		}
+		if tag != "" {
+			for i := firstLineOfThisLine; i < len(allFormattedLinesFromSegments); i++ {
+				switch allFormattedLinesFromSegments[i] {
+				case "。\n", "、\n":
+					// 句読点のみの行は無音行のままにする
+				default:
+					allFormattedLinesFromSegments[i] = tag + " " + allFormattedLinesFromSegments[i]
+				}
+			}
+		}
 	}
 
-	var resultLines []string
+	var resultLines []Segment
 	for _, lineWithNewline := range allFormattedLinesFromSegments {
 		line := strings.TrimSuffix(lineWithNewline, "\n")
 		// 指示: 「改行のみの行は空文字列として扱う」
 		// 指示: 「句読点の連続などによって、実質的に文字を含まない行（改行コードのみに相当する行）が生成される場合、その行はスライス内で空文字列 ("") として表現してください」
 		// これらを考慮し、line が "" (元が "\n")、"。"、"、" の場合に空文字列 "" を結果スライスに追加する。
-		if line == "" || line == "。" || line == "、" {
-			resultLines = append(resultLines, "")
-		} else {
-			resultLines = append(resultLines, line)
+		// 元が空行のものは段落の区切り、句読点のみのものは文の区切りとして区別する。
+		switch line {
+		case "":
+			resultLines = append(resultLines, Segment{Break: BreakParagraph})
+		case "。", "、":
+			resultLines = append(resultLines, Segment{Break: BreakSentence})
+		default:
+			resultLines = append(resultLines, Segment{Text: line})
 		}
 	}
 
@@ -250,3 +250,45 @@ func TestCreate(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateSegmentsBreaks(t *testing.T) {
+	lineNum := 20
+	tmpFile := createTempFile(t, "最初の文です。。次の文です。\n\n次の段落です。")
+	got, err := CreateSegments(tmpFile, &lineNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Segment{
+		{Text: "最初の文です。"},
+		{Break: BreakSentence},
+		{Text: "次の文です。"},
+		{Break: BreakParagraph},
+		{Text: "次の段落です。"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSegmentLinesRoundTrip(t *testing.T) {
+	segments := []Segment{
+		{Text: "最初の文です。"},
+		{Break: BreakSentence},
+		{Text: "次の文です。"},
+		{Break: BreakParagraph},
+		{Text: "次の段落です。"},
+	}
+	lines := SegmentLines(segments)
+	if want := []string{"最初の文です。", "。", "次の文です。", "", "次の段落です。"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	script, err := ParseScript(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range script {
+		if l.Text != segments[i].Text || l.Break != segments[i].Break {
+			t.Errorf("%d行目: got %q %v, want %q %v", i+1, l.Text, l.Break, segments[i].Text, segments[i].Break)
+		}
+	}
+}
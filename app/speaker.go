@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SpeakerConfig は台本の話者ラベルとVOICEVOXのスタイルの対応表です。
+// スタイルはIDの数値か、"話者名/スタイル名" で指定します。
+//
+//	default: ずんだもん
+//	speakers:
+//	  ずんだもん: 3
+//	  めたん: 四国めたん/ノーマル
+type SpeakerConfig struct {
+	// Default はラベルのない行(最初のラベルより前の行)に使う話者名です。
+	Default string `yaml:"default"`
+	// Speakers は話者名からスタイルへの対応です。
+	Speakers map[string]StyleRef `yaml:"speakers"`
+
+	// ids は Resolve で解決したスタイルIDです。
+	ids map[string]int
+}
+
+// Resolve は名前で指定されたスタイルをエンジンの /speakers を使ってIDに解決します。
+// 全てのスタイルがIDで指定されている場合はエンジンに問い合わせません。
+func (c *SpeakerConfig) Resolve(ctx context.Context, client *Client) error {
+	if c == nil {
+		return nil
+	}
+	ids, err := c.numericIDs()
+	if err == nil {
+		c.ids = ids
+		return nil
+	}
+	speakers, err := client.Speakers(ctx)
+	if err != nil {
+		return fmt.Errorf("話者一覧の取得に失敗しました: %w", err)
+	}
+	ids = make(map[string]int, len(c.Speakers))
+	for name, ref := range c.Speakers {
+		id, err := ResolveStyle(speakers, ref)
+		if err != nil {
+			return fmt.Errorf("話者 %s: %w", name, err)
+		}
+		ids[name] = id
+	}
+	c.ids = ids
+	return nil
+}
+
+// numericIDs は全てのスタイルがIDで指定されている場合に、その対応を返します。
+func (c *SpeakerConfig) numericIDs() (map[string]int, error) {
+	ids := make(map[string]int, len(c.Speakers))
+	for name, ref := range c.Speakers {
+		id, ok := ref.ID()
+		if !ok {
+			return nil, fmt.Errorf("話者 %s のスタイル %q はエンジンで解決する必要があります", name, string(ref))
+		}
+		ids[name] = id
+	}
+	return ids, nil
+}
+
+// LoadSpeakerConfig はYAML形式の話者設定ファイルを読み込みます。
+func LoadSpeakerConfig(path string) (*SpeakerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("話者設定ファイルの読み込みに失敗しました: %w", err)
+	}
+	var c SpeakerConfig
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("話者設定ファイルの解析に失敗しました: %s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("話者設定ファイル %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// validate は Default の話者が対応表にあることを確認します。
+func (c *SpeakerConfig) validate() error {
+	if c.Default != "" {
+		if _, ok := c.Speakers[c.Default]; !ok {
+			return fmt.Errorf("default の話者 %q が speakers にありません", c.Default)
+		}
+	}
+	return nil
+}
+
+// Assign は各行に音声合成に使うスタイルIDを設定します。
+// 名前で指定されたスタイルがある場合は、事前に Resolve を呼んでおく必要があります。
+// ラベルのない行は直前のラベルの話者を引き継ぎ、最初のラベルより前の行は Default の話者、
+// Default も無い場合は fallbackID を使います。
+// 対応表にない名前のラベル("注意：" や "問1:" など)は話者ラベルとみなさず、本文の一部として直前の話者で読みます。
+// c が nil または対応表が空の場合は全ての話者ラベルを本文の一部とみなし、全ての行に fallbackID を使います。
+func (c *SpeakerConfig) Assign(lines []ScriptLine, fallbackID int) error {
+	if c == nil || len(c.Speakers) == 0 {
+		for i := range lines {
+			lines[i].restoreLabel()
+			lines[i].SpeakerID = fallbackID
+		}
+		return nil
+	}
+
+	ids := c.ids
+	if ids == nil {
+		var err error
+		ids, err = c.numericIDs()
+		if err != nil {
+			return err
+		}
+	}
+	current := fallbackID
+	if c.Default != "" {
+		current = ids[c.Default]
+	}
+	for i := range lines {
+		l := &lines[i]
+		if l.Speaker != "" {
+			if id, ok := ids[l.Speaker]; ok {
+				current = id
+			} else {
+				l.restoreLabel()
+			}
+		}
+		l.SpeakerID = current
+	}
+	return nil
+}
+
+// restoreLabel は話者ラベルとみなさなかった行頭の `名前:` を本文と読み方に戻します。
+func (l *ScriptLine) restoreLabel() {
+	if l.label == "" {
+		return
+	}
+	l.Text = l.label + l.Text
+	if l.Reading != "" {
+		l.Reading = l.label + l.Reading
+	}
+	l.Speaker, l.label = "", ""
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpeakerConfigAssign(t *testing.T) {
+	lines := []string{
+		"最初のナレーションです。",
+		"ずんだもん: 問題を読むのだ。",
+		"[speed=1.1] 続きの行なのだ。",
+		"めたん：[pitch=0.02] 解説するわね、",
+		"ここも続きよ。",
+		"",
+		"[speed=0.9] ずんだもん: わかったのだ。",
+	}
+	script, err := ParseScript(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &SpeakerConfig{
+		Default:  "ずんだもん",
+		Speakers: map[string]StyleRef{"ずんだもん": "3", "めたん": "2"},
+	}
+	if err := c.Assign(script, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		text string
+		id   int
+	}{
+		{"最初のナレーションです。", 3},
+		{"問題を読むのだ。", 3},
+		{"続きの行なのだ。", 3},
+		{"解説するわね、", 2},
+		{"ここも続きよ。", 2},
+		{"", 2},
+		{"わかったのだ。", 3},
+	}
+	for i, w := range want {
+		if script[i].Text != w.text || script[i].SpeakerID != w.id {
+			t.Errorf("%d行目: got (%q, %d), want (%q, %d)", i+1, script[i].Text, script[i].SpeakerID, w.text, w.id)
+		}
+	}
+	if script[3].Prosody.Pitch == nil || script[6].Prosody.Speed == nil {
+		t.Error("ラベルとタグの両方がある行のタグが解析されていない")
+	}
+}
+
+func TestSpeakerConfigAssignUnknown(t *testing.T) {
+	script, err := ParseScript([]string{
+		"ずんだもん: こんにちは。",
+		"注意：この問題は難しい。",
+		"例: 一つ目の場合です。",
+		"めたん：問1: 次の文を読みなさい。",
+		"問1: 次の文を読みなさい。",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &SpeakerConfig{Speakers: map[string]StyleRef{"ずんだもん": "3", "めたん": "2"}}
+	if err := c.Assign(script, 1); err != nil {
+		t.Fatal(err)
+	}
+	// 対応表にない名前のラベルは本文に戻し、直前の話者で読む
+	want := []struct {
+		speaker string
+		text    string
+		id      int
+	}{
+		{"ずんだもん", "こんにちは。", 3},
+		{"", "注意：この問題は難しい。", 3},
+		{"", "例: 一つ目の場合です。", 3},
+		{"めたん", "問1: 次の文を読みなさい。", 2},
+		{"", "問1: 次の文を読みなさい。", 2},
+	}
+	for i, w := range want {
+		l := script[i]
+		if l.Speaker != w.speaker || l.Text != w.text || l.SpeakerID != w.id {
+			t.Errorf("%d行目: got (%q, %q, %d), want (%q, %q, %d)", i+1, l.Speaker, l.Text, l.SpeakerID, w.speaker, w.text, w.id)
+		}
+	}
+}
+
+func TestSpeakerConfigAssignWithoutConfig(t *testing.T) {
+	script, err := ParseScript([]string{"注意：これは本文です。", "10:30に集合です。"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c *SpeakerConfig
+	if err := c.Assign(script, 1); err != nil {
+		t.Fatal(err)
+	}
+	if script[0].Text != "注意：これは本文です。" || script[0].SpeakerID != 1 {
+		t.Errorf("話者設定がない場合はラベルを本文に戻すはず: %+v", script[0])
+	}
+	if script[1].Text != "10:30に集合です。" {
+		t.Errorf("時刻はラベルとみなさないはず: %+v", script[1])
+	}
+}
+
+func TestLoadSpeakerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "speakers.yaml")
+	err := os.WriteFile(path, []byte("default: めたん\nspeakers:\n  ずんだもん: 3\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSpeakerConfig(path); err == nil {
+		t.Error("default の話者が speakers にない場合はエラーになるはず")
+	}
+}
+
+func TestLoadSpeakerConfigStyleRef(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "speakers.yaml")
+	err := os.WriteFile(path, []byte("default: ずんだもん\nspeakers:\n  ずんだもん: 3\n  めたん: 四国めたん/ノーマル\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadSpeakerConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := c.Speakers["ずんだもん"].ID(); !ok || id != 3 {
+		t.Errorf("ずんだもん = %q", c.Speakers["ずんだもん"])
+	}
+	if c.Speakers["めたん"] != "四国めたん/ノーマル" {
+		t.Errorf("めたん = %q", c.Speakers["めたん"])
+	}
+	// 名前で指定されたスタイルは Resolve しないと使えない
+	if err := c.Assign([]ScriptLine{{Number: 1, Text: "テスト"}}, 1); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Style は話者のスタイル(声色)です。音声合成ではスタイルの ID を speaker として指定します。
+type Style struct {
+	Name string `json:"name"`
+	ID   int    `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+// Speaker はエンジンの /speakers が返す話者です。
+type Speaker struct {
+	Name              string            `json:"name"`
+	SpeakerUUID       string            `json:"speaker_uuid"`
+	Styles            []Style           `json:"styles"`
+	Version           string            `json:"version"`
+	SupportedFeatures map[string]string `json:"supported_features,omitempty"`
+}
+
+// StyleInfo は /speaker_info が返すスタイルごとの追加情報です。画像と音声はBase64文字列です。
+type StyleInfo struct {
+	ID           int      `json:"id"`
+	Icon         string   `json:"icon"`
+	Portrait     string   `json:"portrait,omitempty"`
+	VoiceSamples []string `json:"voice_samples"`
+}
+
+// SpeakerInfo はエンジンの /speaker_info が返す話者の追加情報です。
+type SpeakerInfo struct {
+	Policy     string      `json:"policy"`
+	Portrait   string      `json:"portrait"`
+	StyleInfos []StyleInfo `json:"style_infos"`
+}
+
+// Speakers はエンジンに登録されている話者とスタイルの一覧を取得します。
+func (c *Client) Speakers(ctx context.Context) ([]Speaker, error) {
+	data, err := c.do(ctx, http.MethodGet, "/speakers", nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	var speakers []Speaker
+	if err := json.Unmarshal(data, &speakers); err != nil {
+		return nil, fmt.Errorf("話者一覧の解析に失敗しました: %w", err)
+	}
+	return speakers, nil
+}
+
+// SpeakerInfo は speakerUUID の話者の利用規約や画像などの追加情報を取得します。
+func (c *Client) SpeakerInfo(ctx context.Context, speakerUUID string) (*SpeakerInfo, error) {
+	q := url.Values{}
+	q.Set("speaker_uuid", speakerUUID)
+	data, err := c.do(ctx, http.MethodGet, "/speaker_info", q, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	var info SpeakerInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("話者情報の解析に失敗しました: %w", err)
+	}
+	return &info, nil
+}
+
+// StyleRef はスタイルIDの数値、または "話者名/スタイル名" 形式の文字列によるスタイルの指定です。
+// スタイル名を省略した "話者名" の場合は、その話者の最初のスタイルを指します。
+type StyleRef string
+
+// ID は数値で指定されたスタイルIDを返します。名前で指定されている場合は ok が false になります。
+func (r StyleRef) ID() (id int, ok bool) {
+	id, err := strconv.Atoi(strings.TrimSpace(string(r)))
+	return id, err == nil
+}
+
+// ResolveStyle は ref を speakers の中から探し、スタイルIDを返します。
+func ResolveStyle(speakers []Speaker, ref StyleRef) (int, error) {
+	if id, ok := ref.ID(); ok {
+		for _, sp := range speakers {
+			for _, st := range sp.Styles {
+				if st.ID == id {
+					return id, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("%w: speaker=%d", ErrUnknownSpeaker, id)
+	}
+
+	name, style, _ := strings.Cut(string(ref), "/")
+	name, style = strings.TrimSpace(name), strings.TrimSpace(style)
+	for _, sp := range speakers {
+		if sp.Name != name {
+			continue
+		}
+		if style == "" && len(sp.Styles) > 0 {
+			return sp.Styles[0].ID, nil
+		}
+		var names []string
+		for _, st := range sp.Styles {
+			if st.Name == style {
+				return st.ID, nil
+			}
+			names = append(names, st.Name)
+		}
+		return 0, fmt.Errorf("%w: %s にスタイル %q はありません (%s)", ErrUnknownSpeaker, name, style, strings.Join(names, ", "))
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownSpeaker, string(ref))
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const engineSpeakers = `[
+  {"name": "四国めたん", "speaker_uuid": "7ffcb7ce-00ec-4bdc-82cd-45a8889e43ff", "styles": [{"name": "ノーマル", "id": 2}, {"name": "あまあま", "id": 0}], "version": "0.14.1"},
+  {"name": "ずんだもん", "speaker_uuid": "388f246b-8c41-4ac1-8e2d-5d79f3ff56d9", "styles": [{"name": "ノーマル", "id": 3}, {"name": "あまあま", "id": 1}], "version": "0.14.1"}
+]`
+
+func TestResolveStyle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/speakers" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+		w.Write([]byte(engineSpeakers))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(srv.URL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	speakers, err := client.Speakers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref     StyleRef
+		want    int
+		wantErr bool
+	}{
+		{ref: "ずんだもん/あまあま", want: 1},
+		{ref: "ずんだもん", want: 3},
+		{ref: "四国めたん / ノーマル", want: 2},
+		{ref: "3", want: 3},
+		{ref: "99", wantErr: true},
+		{ref: "ずんだもん/ささやき", wantErr: true},
+		{ref: "春日部つむぎ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.ref), func(t *testing.T) {
+			got, err := ResolveStyle(speakers, tt.ref)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnknownSpeaker) {
+					t.Errorf("expected ErrUnknownSpeaker, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got (%d, %v), want %d", got, err, tt.want)
+			}
+		})
+	}
+
+	c := &SpeakerConfig{Speakers: map[string]StyleRef{"ずんだもん": "ずんだもん/ノーマル", "めたん": "四国めたん"}}
+	if err := c.Resolve(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	script, _ := ParseScript([]string{"めたん: こんにちは。"})
+	if err := c.Assign(script, 1); err != nil || script[0].SpeakerID != 2 {
+		t.Errorf("got (%d, %v)", script[0].SpeakerID, err)
+	}
+}
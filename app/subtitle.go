@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SubtitleFormat は字幕ファイルの形式です。
+type SubtitleFormat int
+
+const (
+	SubtitleSRT SubtitleFormat = iota
+	SubtitleVTT
+)
+
+// Ext は字幕ファイルの拡張子です。
+func (f SubtitleFormat) Ext() string {
+	if f == SubtitleVTT {
+		return ".vtt"
+	}
+	return ".srt"
+}
+
+// ParseSubtitleFormats は "srt,vtt" のようにカンマ区切りで指定された字幕の形式を変換します。
+// 空文字列の場合は nil を返します。
+func ParseSubtitleFormats(s string) ([]SubtitleFormat, error) {
+	var formats []SubtitleFormat
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "srt":
+			formats = append(formats, SubtitleSRT)
+		case "vtt", "webvtt":
+			formats = append(formats, SubtitleVTT)
+		default:
+			return nil, fmt.Errorf("不明な字幕の形式です: %q (srt か vtt を指定してください)", name)
+		}
+	}
+	return formats, nil
+}
+
+// Cue は字幕の1つの表示区間です。
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// shiftCues は cues の時刻を offset だけずらしたコピーを返します。
+func shiftCues(cues []Cue, offset time.Duration) []Cue {
+	shifted := make([]Cue, len(cues))
+	for i, c := range cues {
+		shifted[i] = Cue{Start: c.Start + offset, End: c.End + offset, Text: c.Text}
+	}
+	return shifted
+}
+
+// WriteSubtitles は cues を format の字幕として w に書き込みます。
+func WriteSubtitles(w io.Writer, format SubtitleFormat, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	if format == SubtitleVTT {
+		bw.WriteString("WEBVTT\n")
+	}
+	for i, c := range cues {
+		if format == SubtitleVTT {
+			fmt.Fprintf(bw, "\n%s --> %s\n%s\n", timestamp(c.Start, '.'), timestamp(c.End, '.'), c.Text)
+		} else {
+			if i > 0 {
+				bw.WriteString("\n")
+			}
+			fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n", i+1, timestamp(c.Start, ','), timestamp(c.End, ','), c.Text)
+		}
+	}
+	return bw.Flush()
+}
+
+// timestamp は d を "01:02:03,456" の形式にします。sep はミリ秒の前の区切り文字です(SRT は ',', WebVTT は '.')。
+func timestamp(d time.Duration, sep rune) string {
+	ms := d.Round(time.Millisecond).Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// SaveSubtitles は cues を formats のそれぞれの形式で、音声ファイル wavPath の拡張子を変えたパスに保存します。
+// 保存したファイルのパスを返します。
+func SaveSubtitles(wavPath string, cues []Cue, formats []SubtitleFormat) ([]string, error) {
+	base := strings.TrimSuffix(wavPath, filepath.Ext(wavPath))
+	var paths []string
+	for _, format := range formats {
+		path := base + format.Ext()
+		var b strings.Builder
+		if err := WriteSubtitles(&b, format, cues); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			return nil, fmt.Errorf("字幕ファイルの保存に失敗しました: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"voicevox/wav"
+)
+
+func TestWriteSubtitles(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "こんにちは。"},
+		{Start: 61*time.Minute + 2*time.Second + 345*time.Millisecond, End: 61*time.Minute + 3*time.Second, Text: "ずんだもんなのだ。"},
+	}
+	tests := []struct {
+		format SubtitleFormat
+		want   string
+	}{
+		{
+			format: SubtitleSRT,
+			want: `1
+00:00:00,000 --> 00:00:01,500
+こんにちは。
+
+2
+01:01:02,345 --> 01:01:03,000
+ずんだもんなのだ。
+`,
+		},
+		{
+			format: SubtitleVTT,
+			want: `WEBVTT
+
+00:00:00.000 --> 00:00:01.500
+こんにちは。
+
+01:01:02.345 --> 01:01:03.000
+ずんだもんなのだ。
+`,
+		},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := WriteSubtitles(&b, tt.format, cues); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.format.Ext(), b.String(), tt.want)
+		}
+	}
+}
+
+func TestParseSubtitleFormats(t *testing.T) {
+	got, err := ParseSubtitleFormats("srt, WebVTT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []SubtitleFormat{SubtitleSRT, SubtitleVTT}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, err := ParseSubtitleFormats(""); err != nil || got != nil {
+		t.Errorf("空文字列: got %v, %v", got, err)
+	}
+	if _, err := ParseSubtitleFormats("ass"); err == nil {
+		t.Error("不明な形式はエラーになるはず")
+	}
+}
+
+func TestConcatenatorCues(t *testing.T) {
+	// 無音行は字幕にせず、クロスフェードで重なる区間は次の字幕の開始で終える
+	buf := func(d time.Duration) *wav.Buffer {
+		return &wav.Buffer{SampleRate: 24000, Channels: 1, Samples: make([]float64, int(d.Seconds()*24000))}
+	}
+	out, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	c := NewConcatenator(out, ConcatOptions{Crossfade: 20 * time.Millisecond})
+	for _, seg := range []struct {
+		d    time.Duration
+		text string
+	}{
+		{100 * time.Millisecond, "一"},
+		{100 * time.Millisecond, "二"},
+		{200 * time.Millisecond, ""},
+		{100 * time.Millisecond, "三"},
+	} {
+		if err := c.AddBuffer(buf(seg.d), seg.text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []Cue{
+		{Start: 0, End: 80 * time.Millisecond, Text: "一"},
+		{Start: 80 * time.Millisecond, End: 180 * time.Millisecond, Text: "二"},
+		{Start: 340 * time.Millisecond, End: 440 * time.Millisecond, Text: "三"},
+	}
+	if got := c.Timeline().Cues; !slices.Equal(got, want) {
+		t.Errorf("cues = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// engineFrameRate はエンジンが音素の長さを丸める単位(1秒あたりのフレーム数、24000Hz / 256サンプル)です。
+const engineFrameRate = 24000.0 / 256
+
+// pauseVowel はアクセント句の後ろの無音(pause_mora)の母音です。
+const pauseVowel = "pau"
+
+// MoraTiming は1モーラの音声の中での位置です。
+// アクセント句の後ろの無音は Vowel が "pau" のモーラとして含めます。
+type MoraTiming struct {
+	// Line は台本の行番号(1始まり)です。
+	Line      int
+	Text      string
+	Consonant string
+	Vowel     string
+	// Start は子音(子音がない場合は母音)の開始、VowelStart は母音の開始、End は母音の終了です。
+	Start      time.Duration
+	VowelStart time.Duration
+	End        time.Duration
+}
+
+// frames は秒単位の音素の長さを、エンジンと同じく話速を反映してフレーム単位に丸めた時間にします。
+func (q *AudioQuery) frames(seconds float64) time.Duration {
+	speed := q.SpeedScale
+	if speed <= 0 {
+		speed = 1
+	}
+	n := math.Round(seconds / speed * engineFrameRate)
+	return time.Duration(math.Round(n / engineFrameRate * float64(time.Second)))
+}
+
+// MoraTimings は q から合成される音声の中での各モーラの位置を計算します。line は結果に記録する台本の行番号です。
+// 先頭の無音(prePhonemeLength)の後からモーラを並べ、話速(speedScale)と
+// 句の後ろの無音の長さの指定(pauseLength, pauseLengthScale)を反映します。
+func (q *AudioQuery) MoraTimings(line int) []MoraTiming {
+	var timings []MoraTiming
+	t := q.frames(q.PrePhonemeLength)
+	add := func(m Mora, pause bool) {
+		mt := MoraTiming{Line: line, Text: m.Text, Vowel: m.Vowel, Start: t}
+		if m.Consonant != nil && m.ConsonantLength != nil {
+			mt.Consonant = *m.Consonant
+			t += q.frames(*m.ConsonantLength)
+		}
+		vowel := m.VowelLength
+		if pause {
+			mt.Vowel = pauseVowel
+			if q.PauseLength != nil {
+				vowel = *q.PauseLength
+			}
+			if q.PauseLengthScale != nil {
+				vowel *= *q.PauseLengthScale
+			}
+		}
+		mt.VowelStart = t
+		t += q.frames(vowel)
+		mt.End = t
+		timings = append(timings, mt)
+	}
+	for _, ap := range q.AccentPhrases {
+		for _, m := range ap.Moras {
+			add(m, false)
+		}
+		if ap.PauseMora != nil {
+			add(*ap.PauseMora, true)
+		}
+	}
+	return timings
+}
+
+// shiftMoras は timings の時刻を offset だけずらしたコピーを返します。
+func shiftMoras(timings []MoraTiming, offset time.Duration) []MoraTiming {
+	shifted := make([]MoraTiming, len(timings))
+	for i, m := range timings {
+		m.Start += offset
+		m.VowelStart += offset
+		m.End += offset
+		shifted[i] = m
+	}
+	return shifted
+}
+
+// TimingFormat はモーラのタイミングのファイルの形式です。
+type TimingFormat int
+
+const (
+	TimingJSON TimingFormat = iota
+	TimingCSV
+)
+
+// Ext はタイミングのファイルの拡張子です。字幕や音声と区別するため ".timing" を付けます。
+func (f TimingFormat) Ext() string {
+	if f == TimingCSV {
+		return ".timing.csv"
+	}
+	return ".timing.json"
+}
+
+// ParseTimingFormats は "json,csv" のようにカンマ区切りで指定されたタイミングのファイルの形式を変換します。
+// 空文字列の場合は nil を返します。
+func ParseTimingFormats(s string) ([]TimingFormat, error) {
+	var formats []TimingFormat
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "json":
+			formats = append(formats, TimingJSON)
+		case "csv":
+			formats = append(formats, TimingCSV)
+		default:
+			return nil, fmt.Errorf("不明なタイミングの形式です: %q (json か csv を指定してください)", name)
+		}
+	}
+	return formats, nil
+}
+
+// timingRecord は MoraTiming のファイル上の表現です。時刻は秒単位です。
+type timingRecord struct {
+	Line       int     `json:"line"`
+	Text       string  `json:"text"`
+	Consonant  string  `json:"consonant,omitempty"`
+	Vowel      string  `json:"vowel"`
+	Start      float64 `json:"start"`
+	VowelStart float64 `json:"vowel_start"`
+	End        float64 `json:"end"`
+}
+
+// seconds は d をミリ秒に丸めた秒数にします。
+func seconds(d time.Duration) float64 {
+	return float64(d.Round(time.Millisecond).Milliseconds()) / 1000
+}
+
+// WriteTimings は timings を format の形式で w に書き込みます。
+// JSON は {"moras": [...]} の形式、CSV は1行目を見出しとする1モーラ1行の形式です。
+func WriteTimings(w io.Writer, format TimingFormat, timings []MoraTiming) error {
+	if format == TimingCSV {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"line", "text", "consonant", "vowel", "start", "vowel_start", "end"})
+		for _, m := range timings {
+			cw.Write([]string{
+				strconv.Itoa(m.Line), m.Text, m.Consonant, m.Vowel,
+				strconv.FormatFloat(seconds(m.Start), 'f', 3, 64),
+				strconv.FormatFloat(seconds(m.VowelStart), 'f', 3, 64),
+				strconv.FormatFloat(seconds(m.End), 'f', 3, 64),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	records := make([]timingRecord, len(timings))
+	for i, m := range timings {
+		records[i] = timingRecord{
+			Line: m.Line, Text: m.Text, Consonant: m.Consonant, Vowel: m.Vowel,
+			Start: seconds(m.Start), VowelStart: seconds(m.VowelStart), End: seconds(m.End),
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Moras []timingRecord `json:"moras"`
+	}{records})
+}
+
+// SaveTimings は timings を formats のそれぞれの形式で、音声ファイル wavPath の拡張子を変えたパスに保存します。
+// 保存したファイルのパスを返します。
+func SaveTimings(wavPath string, timings []MoraTiming, formats []TimingFormat) ([]string, error) {
+	base := strings.TrimSuffix(wavPath, filepath.Ext(wavPath))
+	var paths []string
+	for _, format := range formats {
+		path := base + format.Ext()
+		var b strings.Builder
+		if err := WriteTimings(&b, format, timings); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			return nil, fmt.Errorf("タイミングのファイルの保存に失敗しました: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
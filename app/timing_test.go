@@ -0,0 +1,118 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"voicevox/wav"
+)
+
+func timingQuery() *AudioQuery {
+	s := func(v string) *string { return &v }
+	f := func(v float64) *float64 { return &v }
+	// 長さはエンジンのフレーム(1/93.75秒)の倍数にしておく
+	return &AudioQuery{
+		AccentPhrases: []AccentPhrase{
+			{
+				Moras: []Mora{
+					{Text: "ズ", Consonant: s("z"), ConsonantLength: f(0.064), Vowel: "u", VowelLength: 0.128},
+					{Text: "ン", Vowel: "N", VowelLength: 0.064},
+				},
+				PauseMora: &Mora{Text: "、", Vowel: "pau", VowelLength: 0.064},
+			},
+			{
+				Moras: []Mora{
+					{Text: "ダ", Consonant: s("d"), ConsonantLength: f(0.064), Vowel: "a", VowelLength: 0.192},
+				},
+			},
+		},
+		SpeedScale:        2,
+		PrePhonemeLength:  0.192,
+		PostPhonemeLength: 0.192,
+		PauseLengthScale:  f(2),
+	}
+}
+
+func TestMoraTimings(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+	got := timingQuery().MoraTimings(3)
+	// 話速2倍で全ての長さが半分になり、句の後ろの無音は pauseLengthScale で2倍になる
+	want := []MoraTiming{
+		{Line: 3, Text: "ズ", Consonant: "z", Vowel: "u", Start: ms(96), VowelStart: ms(128), End: ms(192)},
+		{Line: 3, Text: "ン", Vowel: "N", Start: ms(192), VowelStart: ms(192), End: ms(224)},
+		{Line: 3, Text: "、", Vowel: "pau", Start: ms(224), VowelStart: ms(224), End: ms(288)},
+		{Line: 3, Text: "ダ", Consonant: "d", Vowel: "a", Start: ms(288), VowelStart: ms(320), End: ms(416)},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	// pauseLength を指定した場合はエンジンの無音の長さを置き換える
+	q := timingQuery()
+	pause := 0.032
+	q.PauseLength = &pause
+	if got := q.MoraTimings(3)[2]; got.End-got.Start != ms(32) {
+		t.Errorf("pause = %v, want 32ms", got.End-got.Start)
+	}
+}
+
+func TestWriteTimings(t *testing.T) {
+	timings := timingQuery().MoraTimings(1)[:2]
+	var b strings.Builder
+	if err := WriteTimings(&b, TimingCSV, timings); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "line,text,consonant,vowel,start,vowel_start,end\n1,ズ,z,u,0.096,0.128,0.192\n1,ン,,N,0.192,0.192,0.224\n"
+	if b.String() != wantCSV {
+		t.Errorf("csv:\n%s\nwant:\n%s", b.String(), wantCSV)
+	}
+
+	b.Reset()
+	if err := WriteTimings(&b, TimingJSON, timings); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"moras": [`, `"consonant": "z"`, `"vowel_start": 0.128`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("json に %s が含まれていない:\n%s", want, b.String())
+		}
+	}
+	if strings.Count(b.String(), `"consonant"`) != 1 {
+		t.Errorf("子音のないモーラは consonant を省略するはず:\n%s", b.String())
+	}
+}
+
+func TestConcatResultsTiming(t *testing.T) {
+	// 2行目のモーラは1行目の音声と行間の無音の後ろにずれる
+	audio := func(d time.Duration) []byte {
+		var buf bytes.Buffer
+		if err := wav.Encode(&buf, wav.Silence(EngineFormat, d)); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	results := slices.Values([]LineResult{
+		{Index: 0, Line: ScriptLine{Number: 1, Text: "ずん、だ"}, Audio: audio(500 * time.Millisecond), Query: timingQuery()},
+		{Index: 1, Line: ScriptLine{Number: 2}, Audio: audio(300 * time.Millisecond)},
+		{Index: 2, Line: ScriptLine{Number: 3, Text: "ずん、だ"}, Audio: audio(500 * time.Millisecond), Query: timingQuery()},
+	})
+	dir := t.TempDir()
+	timeline, err := ConcatResultsToFile(context.Background(), dir+"/out.wav", results, ConcatOptions{Timing: []TimingFormat{TimingJSON, TimingCSV}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeline.Moras) != 8 {
+		t.Fatalf("%d モーラ, want 8", len(timeline.Moras))
+	}
+	if got := timeline.Moras[4]; got.Line != 3 || got.Start != 896*time.Millisecond {
+		t.Errorf("3行目の最初のモーラ = %+v, want start 896ms", got)
+	}
+	for _, ext := range []string{".timing.json", ".timing.csv"} {
+		if _, err := os.Stat(dir + "/out" + ext); err != nil {
+			t.Errorf("out%s が保存されていない: %v", ext, err)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 単語の品詞。エンジンの word_type に指定する値です。
+const (
+	WordProperNoun = "PROPER_NOUN"
+	WordCommonNoun = "COMMON_NOUN"
+	WordVerb       = "VERB"
+	WordAdjective  = "ADJECTIVE"
+	WordSuffix     = "SUFFIX"
+)
+
+// DefaultWordPriority は単語の優先度の既定値です(0〜10、大きいほど優先)。
+const DefaultWordPriority = 5
+
+// wordType は品詞と、エンジンが登録時に設定する品詞・品詞細分類1の対応です。
+type wordType struct {
+	name, pos, detail string
+}
+
+var wordTypes = []wordType{
+	{WordProperNoun, "名詞", "固有名詞"},
+	{WordCommonNoun, "名詞", "一般"},
+	{WordVerb, "動詞", "自立"},
+	{WordAdjective, "形容詞", "自立"},
+	{WordSuffix, "名詞", "接尾"},
+}
+
+// UserDictWord はエンジンの /user_dict が返すユーザー辞書の単語です。
+type UserDictWord struct {
+	Surface             string `json:"surface"`
+	Priority            int    `json:"priority"`
+	PartOfSpeech        string `json:"part_of_speech"`
+	PartOfSpeechDetail1 string `json:"part_of_speech_detail_1"`
+	Pronunciation       string `json:"pronunciation"`
+	AccentType          int    `json:"accent_type"`
+	MoraCount           int    `json:"mora_count"`
+}
+
+// WordType は単語の品詞を DictWord.WordType の値で返します。該当しない場合は空文字列です。
+func (w UserDictWord) WordType() string {
+	for _, t := range wordTypes {
+		if t.pos == w.PartOfSpeech && t.detail == w.PartOfSpeechDetail1 {
+			return t.name
+		}
+	}
+	return ""
+}
+
+// DictWord は読み方の辞書ファイルの1単語です。
+type DictWord struct {
+	// Surface は単語の表記です。
+	Surface string `yaml:"surface"`
+	// Pronunciation はカタカナの読み方です。
+	Pronunciation string `yaml:"pronunciation"`
+	// AccentType はアクセント核の位置(音が下がる直前のモーラ、0は平板型)です。
+	AccentType int `yaml:"accent_type"`
+	// WordType は品詞です(PROPER_NOUN, COMMON_NOUN, VERB, ADJECTIVE, SUFFIX)。省略した場合は PROPER_NOUN です。
+	WordType string `yaml:"word_type,omitempty"`
+	// Priority は優先度(0〜10)です。省略した場合は DefaultWordPriority です。
+	Priority *int `yaml:"priority,omitempty"`
+}
+
+func (w DictWord) wordType() string {
+	if w.WordType == "" {
+		return WordProperNoun
+	}
+	return strings.ToUpper(w.WordType)
+}
+
+func (w DictWord) priority() int {
+	if w.Priority == nil {
+		return DefaultWordPriority
+	}
+	return *w.Priority
+}
+
+// key は表記の比較に使う値です。エンジンは表記を全角に変換して保存するため、全角にそろえます。
+func (w DictWord) key() string {
+	return toZenkaku(w.Surface)
+}
+
+// toZenkaku は ASCII の表示可能な文字を全角に変換します。エンジンの変換に合わせています。
+func toZenkaku(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == ' ':
+			return '　'
+		case r >= '!' && r <= '~':
+			return r + 0xFEE0
+		}
+		return r
+	}, s)
+}
+
+// moraCount はカタカナの読み方のモーラ数を返します。小書きの「ャュョァィゥェォヮ」は前の文字と合わせて1モーラです。
+func moraCount(pronunciation string) int {
+	n := 0
+	for _, r := range pronunciation {
+		if !strings.ContainsRune("ャュョァィゥェォヮ", r) {
+			n++
+		}
+	}
+	return n
+}
+
+// isKatakana は s がカタカナと長音符だけでできているかを返します。
+func isKatakana(s string) bool {
+	for _, r := range s {
+		if (r < 'ァ' || r > 'ヴ') && r != 'ー' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func (w DictWord) validate() error {
+	if strings.TrimSpace(w.Surface) == "" {
+		return errors.New("surface を指定してください")
+	}
+	if !isKatakana(w.Pronunciation) {
+		return fmt.Errorf("%s: pronunciation はカタカナで指定してください: %q", w.Surface, w.Pronunciation)
+	}
+	if n := moraCount(w.Pronunciation); w.AccentType < 0 || w.AccentType > n {
+		return fmt.Errorf("%s: accent_type は0から%d(モーラ数)の間で指定してください: %d", w.Surface, n, w.AccentType)
+	}
+	if !slices.ContainsFunc(wordTypes, func(t wordType) bool { return t.name == w.wordType() }) {
+		return fmt.Errorf("%s: word_type は %s のいずれかを指定してください: %q", w.Surface, wordTypeNames(), w.WordType)
+	}
+	if p := w.priority(); p < 0 || p > 10 {
+		return fmt.Errorf("%s: priority は0から10の間で指定してください: %d", w.Surface, p)
+	}
+	return nil
+}
+
+// matches はエンジンの単語 e が w と同じ内容かどうかを返します。
+func (w DictWord) matches(e UserDictWord) bool {
+	return e.Surface == w.key() && e.Pronunciation == w.Pronunciation && e.AccentType == w.AccentType &&
+		e.Priority == w.priority() && e.WordType() == w.wordType()
+}
+
+// Dictionary はプロジェクトの読み方の辞書ファイルです。
+//
+//	words:
+//	  - surface: FP
+//	    pronunciation: エフピー
+//	    accent_type: 3
+//	  - surface: 年金
+//	    pronunciation: ネンキン
+//	    accent_type: 1
+//	    word_type: COMMON_NOUN
+type Dictionary struct {
+	Words []DictWord `yaml:"words"`
+}
+
+// LoadDictionary は読み方の辞書ファイルを読み込みます。
+func LoadDictionary(path string) (*Dictionary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("辞書ファイルの読み込みに失敗しました: %w", err)
+	}
+	var d Dictionary
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("辞書ファイルの解析に失敗しました: %s: %w", path, err)
+	}
+	if err := d.validate(); err != nil {
+		return nil, fmt.Errorf("辞書ファイル %s: %w", path, err)
+	}
+	return &d, nil
+}
+
+func (d *Dictionary) validate() error {
+	seen := map[string]bool{}
+	var errs []error
+	for _, w := range d.Words {
+		if err := w.validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen[w.key()] {
+			errs = append(errs, fmt.Errorf("%s: 同じ表記の単語が複数あります", w.Surface))
+		}
+		seen[w.key()] = true
+	}
+	return errors.Join(errs...)
+}
+
+// Write は辞書をYAML形式で w に書き出します。
+func (d *Dictionary) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Save は辞書を path に保存します。
+func (d *Dictionary) Save(path string) error {
+	var buf bytes.Buffer
+	if err := d.Write(&buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("辞書ファイルの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// DictSyncResult は辞書の同期で行った変更の数です。
+type DictSyncResult struct {
+	Added, Updated, Unchanged, Deleted int
+}
+
+func (r DictSyncResult) String() string {
+	return fmt.Sprintf("追加 %d、更新 %d、変更なし %d、削除 %d", r.Added, r.Updated, r.Unchanged, r.Deleted)
+}
+
+// SyncDictionary はエンジンのユーザー辞書を d に合わせます。
+// 同じ表記の単語がなければ追加し、内容が異なれば更新し、同じであれば何もしないため、何度実行しても同じ結果になります。
+// prune が true の場合は d にない単語をエンジンから削除します。
+func SyncDictionary(ctx context.Context, c *Client, d *Dictionary, prune bool) (DictSyncResult, error) {
+	var result DictSyncResult
+	current, err := c.UserDict(ctx)
+	if err != nil {
+		return result, err
+	}
+	// 表記から単語のUUIDへの対応。同じ表記の単語が複数ある場合はUUIDの小さいものを使う
+	uuids := slices.Sorted(maps.Keys(current))
+	bySurface := map[string]string{}
+	for _, id := range uuids {
+		if _, ok := bySurface[current[id].Surface]; !ok {
+			bySurface[current[id].Surface] = id
+		}
+	}
+
+	keep := map[string]bool{}
+	for _, w := range d.Words {
+		id, ok := bySurface[w.key()]
+		switch {
+		case !ok:
+			id, err = c.AddUserDictWord(ctx, w)
+			result.Added++
+		case w.matches(current[id]):
+			result.Unchanged++
+		default:
+			err = c.UpdateUserDictWord(ctx, id, w)
+			result.Updated++
+		}
+		if err != nil {
+			return result, fmt.Errorf("単語 %s の登録に失敗しました: %w", w.Surface, err)
+		}
+		keep[id] = true
+	}
+	if prune {
+		for _, id := range uuids {
+			if keep[id] {
+				continue
+			}
+			if err := c.DeleteUserDictWord(ctx, id); err != nil {
+				return result, fmt.Errorf("単語 %s の削除に失敗しました: %w", current[id].Surface, err)
+			}
+			result.Deleted++
+		}
+	}
+	return result, nil
+}
+
+// ExportDictionary はエンジンのユーザー辞書の単語を表記の順に並べた辞書を返します。
+func ExportDictionary(ctx context.Context, c *Client) (*Dictionary, error) {
+	current, err := c.UserDict(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dictionary{}
+	for _, e := range current {
+		w := DictWord{Surface: e.Surface, Pronunciation: e.Pronunciation, AccentType: e.AccentType, WordType: e.WordType()}
+		if e.Priority != DefaultWordPriority {
+			p := e.Priority
+			w.Priority = &p
+		}
+		d.Words = append(d.Words, w)
+	}
+	slices.SortFunc(d.Words, func(a, b DictWord) int { return strings.Compare(a.Surface, b.Surface) })
+	return d, nil
+}
+
+// Hash は辞書の単語の内容から計算した値です。単語の順序には依存しません。
+func (d *Dictionary) Hash() string {
+	words := make([]string, len(d.Words))
+	for i, w := range d.Words {
+		data, _ := json.Marshal(w)
+		words[i] = string(data)
+	}
+	slices.Sort(words)
+	sum := sha256.Sum256([]byte(strings.Join(words, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// UserDict はエンジンのユーザー辞書の単語を、単語のUUIDをキーにして取得します。
+func (c *Client) UserDict(ctx context.Context) (map[string]UserDictWord, error) {
+	data, err := c.do(ctx, http.MethodGet, "/user_dict", nil, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	var words map[string]UserDictWord
+	if err := json.Unmarshal(data, &words); err != nil {
+		return nil, fmt.Errorf("ユーザー辞書の解析に失敗しました: %w", err)
+	}
+	return words, nil
+}
+
+// wordQuery は単語の登録と更新のクエリパラメーターです。
+func wordQuery(w DictWord) url.Values {
+	q := url.Values{}
+	q.Set("surface", w.Surface)
+	q.Set("pronunciation", w.Pronunciation)
+	q.Set("accent_type", strconv.Itoa(w.AccentType))
+	q.Set("word_type", w.wordType())
+	q.Set("priority", strconv.Itoa(w.priority()))
+	return q
+}
+
+// AddUserDictWord はユーザー辞書に単語を追加し、単語のUUIDを返します。
+func (c *Client) AddUserDictWord(ctx context.Context, w DictWord) (string, error) {
+	data, err := c.do(ctx, http.MethodPost, "/user_dict_word", wordQuery(w), nil, -1)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	if err := json.Unmarshal(data, &id); err != nil {
+		return "", fmt.Errorf("単語のUUIDの解析に失敗しました: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateUserDictWord はユーザー辞書の uuid の単語を w の内容に更新します。
+func (c *Client) UpdateUserDictWord(ctx context.Context, uuid string, w DictWord) error {
+	_, err := c.do(ctx, http.MethodPut, "/user_dict_word/"+url.PathEscape(uuid), wordQuery(w), nil, -1)
+	return err
+}
+
+// DeleteUserDictWord はユーザー辞書から uuid の単語を削除します。
+func (c *Client) DeleteUserDictWord(ctx context.Context, uuid string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/user_dict_word/"+url.PathEscape(uuid), nil, nil, -1)
+	return err
+}
+
+// wordTypeNames は有効な品詞の一覧です。エラーメッセージに使います。
+func wordTypeNames() string {
+	names := make([]string, len(wordTypes))
+	for i, t := range wordTypes {
+		names[i] = t.name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,201 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUserDict はエンジンのユーザー辞書のAPIだけを実装したサーバーです。
+type fakeUserDict struct {
+	*httptest.Server
+	mu    sync.Mutex
+	words map[string]UserDictWord
+	next  int
+	// writes は POST, PUT, DELETE の回数です。
+	writes int
+}
+
+func newFakeUserDict(t *testing.T) *fakeUserDict {
+	t.Helper()
+	d := &fakeUserDict{words: map[string]UserDictWord{}}
+	word := func(r *http.Request) (UserDictWord, bool) {
+		q := r.URL.Query()
+		accent, err1 := strconv.Atoi(q.Get("accent_type"))
+		priority, err2 := strconv.Atoi(q.Get("priority"))
+		w := UserDictWord{Surface: toZenkaku(q.Get("surface")), Pronunciation: q.Get("pronunciation"), AccentType: accent, Priority: priority, MoraCount: moraCount(q.Get("pronunciation"))}
+		for _, t := range wordTypes {
+			if t.name == q.Get("word_type") {
+				w.PartOfSpeech, w.PartOfSpeechDetail1 = t.pos, t.detail
+			}
+		}
+		return w, err1 == nil && err2 == nil && w.PartOfSpeech != ""
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /user_dict", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		json.NewEncoder(w).Encode(d.words)
+	})
+	mux.HandleFunc("POST /user_dict_word", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		word, ok := word(r)
+		if !ok {
+			http.Error(w, `{"detail":"invalid"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		d.writes++
+		d.next++
+		id := fmt.Sprintf("uuid-%d", d.next)
+		d.words[id] = word
+		json.NewEncoder(w).Encode(id)
+	})
+	mux.HandleFunc("PUT /user_dict_word/{id}", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		word, ok := word(r)
+		if _, found := d.words[r.PathValue("id")]; !found || !ok {
+			http.Error(w, `{"detail":"invalid"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		d.writes++
+		d.words[r.PathValue("id")] = word
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /user_dict_word/{id}", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.writes++
+		delete(d.words, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	d.Server = httptest.NewServer(mux)
+	t.Cleanup(d.Close)
+	return d
+}
+
+func TestSyncDictionary(t *testing.T) {
+	engine := newFakeUserDict(t)
+	client, err := NewClient(engine.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// 辞書にない単語
+	if _, err := client.AddUserDictWord(ctx, DictWord{Surface: "ずんだ", Pronunciation: "ズンダ", AccentType: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	three := 3
+	dict := &Dictionary{Words: []DictWord{
+		{Surface: "FP", Pronunciation: "エフピー", AccentType: 3},
+		{Surface: "年金", Pronunciation: "ネンキン", AccentType: 1, WordType: "common_noun", Priority: &three},
+	}}
+	tests := []struct {
+		name   string
+		change func()
+		prune  bool
+		want   DictSyncResult
+	}{
+		{"初回", func() {}, false, DictSyncResult{Added: 2}},
+		{"変更なし", func() {}, false, DictSyncResult{Unchanged: 2}},
+		{"読み方の変更", func() { dict.Words[0].Pronunciation = "エフピイ" }, false, DictSyncResult{Updated: 1, Unchanged: 1}},
+		{"削除", func() {}, true, DictSyncResult{Unchanged: 2, Deleted: 1}},
+		{"削除後", func() {}, true, DictSyncResult{Unchanged: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			got, err := SyncDictionary(ctx, client, dict, tt.prune)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("SyncDictionary() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	writes := engine.writes
+	if _, err := SyncDictionary(ctx, client, dict, true); err != nil {
+		t.Fatal(err)
+	}
+	if engine.writes != writes {
+		t.Errorf("同じ辞書の同期でエンジンへの書き込みが %d 回行われました", engine.writes-writes)
+	}
+
+	exported, err := ExportDictionary(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DictWord{
+		{Surface: "年金", Pronunciation: "ネンキン", AccentType: 1, WordType: WordCommonNoun, Priority: &three},
+		{Surface: "ＦＰ", Pronunciation: "エフピイ", AccentType: 3, WordType: WordProperNoun},
+	}
+	if len(exported.Words) != len(want) {
+		t.Fatalf("ExportDictionary() = %+v, want %+v", exported.Words, want)
+	}
+	for i, w := range exported.Words {
+		if w.Surface != want[i].Surface || w.Pronunciation != want[i].Pronunciation || w.AccentType != want[i].AccentType ||
+			w.WordType != want[i].WordType || w.priority() != want[i].priority() {
+			t.Errorf("ExportDictionary()[%d] = %+v, want %+v", i, w, want[i])
+		}
+	}
+	// 書き出した辞書を保存して読み込み直し、同期しても変更はない
+	path := filepath.Join(t.TempDir(), "dict.yaml")
+	if err := exported.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if exported, err = LoadDictionary(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := SyncDictionary(ctx, client, exported, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (DictSyncResult{Unchanged: 2}) {
+		t.Errorf("書き出した辞書の同期 = %+v", got)
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"正常", "words:\n  - surface: iDeCo\n    pronunciation: イデコ\n    accent_type: 0\n    word_type: proper_noun\n", ""},
+		{"ひらがな", "words:\n  - surface: NISA\n    pronunciation: にーさ\n    accent_type: 1\n", "カタカナ"},
+		{"アクセント", "words:\n  - surface: 年金\n    pronunciation: ネンキン\n    accent_type: 5\n", "0から4"},
+		{"拗音", "words:\n  - surface: 厚生\n    pronunciation: キョウセイ\n    accent_type: 5\n", "0から4"},
+		{"品詞", "words:\n  - surface: 年金\n    pronunciation: ネンキン\n    accent_type: 1\n    word_type: NOUN\n", "word_type"},
+		{"優先度", "words:\n  - surface: 年金\n    pronunciation: ネンキン\n    accent_type: 1\n    priority: 11\n", "priority"},
+		{"重複", "words:\n  - surface: FP\n    pronunciation: エフピー\n    accent_type: 3\n  - surface: ＦＰ\n    pronunciation: エフピー\n    accent_type: 3\n", "複数"},
+		{"不明な項目", "words:\n  - surface: FP\n    yomi: エフピー\n", "yomi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dict.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadDictionary(path)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("LoadDictionary() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("LoadDictionary() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"voicevox/app"
+	"voicevox/wav"
+)
+
+// job はテキストから結合した音声までを作る処理の設定です。build と run で共通に使います。
+type job struct {
+	synth *app.Synthesizer
+	voice voice
+	// lineLength は台本の1行の最大文字数の目安です。
+	lineLength int
+	// input は入力ファイルの形式と変換の設定です。
+	input app.InputOptions
+	// prepared が true の場合は入力を整形済みの台本として扱います。
+	prepared bool
+	workers  int
+	// resume が true の場合は前回の出力を削除せず、保存済みの行を再利用します。
+	resume bool
+	concat app.ConcatOptions
+	// dict を指定した場合、音声合成の前にエンジンのユーザー辞書に登録します。
+	dict *app.Dictionary
+
+	outDir string
+	// tmpDir を指定した場合、1行ごとの音声を <tmpDir>/<name>/ にも保存します(デバッグ、再開用)。
+	tmpDir string
+	// outputName は i 番目の入力の出力ファイル名(拡張子なし)を返します。
+	outputName func(input string, i int) string
+	// join を指定した場合、全ての入力の音声を結合したファイルを <outDir>/<join>.wav にも保存します。
+	// 音量の正規化は結合したファイルだけに行います。
+	join string
+}
+
+// lines は input を台本の行にします。整形済みでない場合は台本ファイルも出力します。
+func (j *job) lines(input, name string) ([]app.ScriptLine, error) {
+	if j.prepared {
+		return app.ReadScriptFile(input, j.input.Encoding)
+	}
+	segments, err := app.CreateSegmentsWith(input, &j.lineLength, j.input)
+	if err != nil {
+		return nil, err
+	}
+	err = app.WriteScriptFile(app.SegmentLines(segments), filepath.Join(j.outDir, name+"_script.txt"))
+	if err != nil {
+		return nil, err
+	}
+	return app.ParseSegments(segments)
+}
+
+// run は inputs のそれぞれについて台本の作成、音声合成、結合を行います。
+func (j *job) run(ctx context.Context, inputs []string) error {
+	startTime := time.Now() // 処理開始時間を記録
+
+	if j.resume && j.tmpDir == "" {
+		return fmt.Errorf("再開するには1行ごとの音声の保存先(tmp)を指定してください")
+	}
+	if j.join != "" {
+		for i, input := range inputs {
+			if j.outputName(input, i) == j.join {
+				return fmt.Errorf("結合したファイルの名前 %q が入力 %s の出力ファイル名と同じです", j.join, input)
+			}
+		}
+	}
+	if err := os.MkdirAll(j.outDir, os.ModePerm); err != nil {
+		return fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	}
+	if j.dict != nil {
+		result, err := app.SyncDictionary(ctx, j.synth.Client, j.dict, false)
+		if err != nil {
+			return fmt.Errorf("ユーザー辞書の同期に失敗しました: %w", err)
+		}
+		fmt.Printf("ユーザー辞書: %s\n", result)
+		// 数字や記号を含む表記もエンジンで辞書と照合されるよう、変換せずに送る
+		if j.synth.Normalizer != nil {
+			var surfaces []string
+			for _, w := range j.dict.Words {
+				surfaces = append(surfaces, w.Surface)
+			}
+			j.synth.Normalizer.Keep(surfaces...)
+		}
+		// 更新後の辞書で保存済みの音声を再利用できるかを判定する
+		if err := j.synth.LoadEngineState(ctx); err != nil {
+			return err
+		}
+	}
+	// 結合する場合は、音量の正規化を結合したファイルだけに行う
+	concat := j.concat
+	if j.join != "" {
+		concat.Normalize = wav.NormalizeOptions{}
+	}
+	var outputs []app.AudioSegment
+	for i, input := range inputs {
+		name := j.outputName(input, i)
+
+		// 台本を作成してファイルに出力する
+		lines, err := j.lines(input, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		if err := j.voice.assign(lines); err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		// 1行ずつ音声合成し、台本の順に結合しながら書き出す
+		p := &app.Pipeline{Synth: j.synth, Workers: j.workers, Resume: j.resume, Queries: len(j.concat.Timing) > 0}
+		if j.tmpDir != "" {
+			p.Dir = filepath.Join(j.tmpDir, name)
+		}
+		results, err := p.Run(ctx, lines)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		output := filepath.Join(j.outDir, name+".wav")
+		timeline, err := app.ConcatResultsToFile(ctx, output, results, concat)
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+		fmt.Println(output)
+		outputs = append(outputs, app.AudioSegment{Path: output, Timeline: timeline})
+	}
+
+	if j.join != "" {
+		output := filepath.Join(j.outDir, j.join+".wav")
+		if _, err := app.ConcatSegmentsToFile(output, outputs, j.concat); err != nil {
+			return err
+		}
+		fmt.Println(output)
+	}
+
+	printCacheStats(j.synth)
+	// 処理時間を計測
+	fmt.Printf("処理時間: %s\n", time.Since(startTime))
+	return nil
+}
+
+func runBuild(ctx context.Context, args []string) error {
+	fs := newFlagSet("build", "テキストファイル...")
+	var (
+		scf scriptFlags
+		sf  synthFlags
+		cf  concatFlags
+	)
+	scf.register(fs)
+	sf.register(fs)
+	cf.register(fs)
+	outDir := fs.String("out", "out", "台本と結合したWAVファイルの出力ディレクトリ")
+	tmpDir := fs.String("tmp", "", "指定した場合、1行ごとの音声ファイルを <tmp>/<入力名>/ にも保存する(デバッグ用。-resume に必要)")
+	prepared := fs.Bool("script", false, "入力を整形済みの台本ファイルとして扱い、台本の作成を行わない")
+	join := fs.String("join", "", "指定した場合、全ての入力の音声を結合したファイルを <out>/<join>.wav にも保存する(音量の正規化はこのファイルだけに行う)")
+	dictFile := fs.String("dict", "", "指定した場合、音声合成の前に読み方の辞書ファイルをエンジンのユーザー辞書に登録する")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("テキストファイルを指定してください")
+	}
+
+	synth, v, err := sf.synthesizer(ctx)
+	if err != nil {
+		return err
+	}
+	opts, err := cf.options()
+	if err != nil {
+		return err
+	}
+	input, err := scf.input()
+	if err != nil {
+		return err
+	}
+	var dict *app.Dictionary
+	if *dictFile != "" {
+		if dict, err = app.LoadDictionary(*dictFile); err != nil {
+			return err
+		}
+	}
+	j := &job{
+		synth:      synth,
+		voice:      v,
+		lineLength: scf.lineLength,
+		input:      input,
+		prepared:   *prepared,
+		workers:    sf.workers,
+		resume:     sf.resume,
+		concat:     opts,
+		dict:       dict,
+		outDir:     *outDir,
+		tmpDir:     *tmpDir,
+		outputName: func(input string, _ int) string { return baseName(input) },
+		join:       *join,
+	}
+	return j.run(ctx, fs.Args())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"voicevox/app"
+)
+
+// wavFiles は args に指定されたファイル、またはディレクトリ内のWAVファイルを名前順に並べて返します。
+func wavFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		st, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.wav"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func runConcat(ctx context.Context, args []string) error {
+	fs := newFlagSet("concat", "WAVファイルまたはディレクトリ...")
+	var cf concatFlags
+	cf.register(fs)
+	output := fs.String("o", "out/output.wav", "結合したWAVファイルの保存先")
+	script := fs.String("script", "", "音声ファイルに対応する台本ファイル。句読点ごとの無音の長さの判定と字幕に使います")
+	encodingName := fs.String("encoding", "auto", "台本ファイルの文字コード(auto, utf-8, shift_jis, euc-jp)。auto は内容から判定する")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	encoding, err := app.ParseEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+	files, err := wavFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return fmt.Errorf("結合するWAVファイルを指定してください")
+	}
+	opts, err := cf.options()
+	if err != nil {
+		return err
+	}
+
+	segments := make([]app.AudioSegment, len(files))
+	for i, file := range files {
+		segments[i] = app.AudioSegment{Path: file}
+	}
+	if *script != "" {
+		lines, err := app.ReadScriptFile(*script, encoding)
+		if err != nil {
+			return err
+		}
+		if len(lines) != len(segments) {
+			return fmt.Errorf("台本の行数(%d)と音声ファイルの数(%d)が一致しません", len(lines), len(segments))
+		}
+		for i, line := range lines {
+			segments[i].Text = line.Text
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*output), os.ModePerm); err != nil {
+		return fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	}
+	if _, err := app.ConcatSegmentsToFile(*output, segments, opts); err != nil {
+		return err
+	}
+	fmt.Println(*output)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"voicevox/app"
+)
+
+func runDict(ctx context.Context, args []string) error {
+	fs := newFlagSet("dict", "<import 辞書ファイル | export [辞書ファイル] | list>")
+	engineURL := fs.String("engine", app.EngineURL(), "VOICEVOXエンジンのURL")
+	prune := fs.Bool("prune", false, "import で辞書ファイルにない単語をエンジンのユーザー辞書から削除する")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("import, export, list のいずれかを指定してください")
+	}
+
+	client, err := app.NewClient(*engineURL, 0)
+	if err != nil {
+		return err
+	}
+
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "import":
+		if len(rest) != 1 {
+			return fmt.Errorf("import には辞書ファイルを1つ指定してください")
+		}
+		dict, err := app.LoadDictionary(rest[0])
+		if err != nil {
+			return err
+		}
+		result, err := app.SyncDictionary(ctx, client, dict, *prune)
+		if err != nil {
+			return err
+		}
+		fmt.Println(result)
+		return nil
+	case "export":
+		if len(rest) > 1 {
+			return fmt.Errorf("export には辞書ファイルを1つだけ指定してください")
+		}
+		dict, err := app.ExportDictionary(ctx, client)
+		if err != nil {
+			return err
+		}
+		if len(rest) == 0 {
+			return dict.Write(os.Stdout)
+		}
+		if err := dict.Save(rest[0]); err != nil {
+			return err
+		}
+		fmt.Println(rest[0])
+		return nil
+	case "list":
+		dict, err := app.ExportDictionary(ctx, client)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "表記\t読み方\tアクセント\t品詞")
+		for _, word := range dict.Words {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", word.Surface, word.Pronunciation, word.AccentType, word.WordType)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("不明なサブコマンドです: dict %s", cmd)
+	}
+}
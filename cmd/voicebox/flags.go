@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+	"voicevox/app"
+	"voicevox/wav"
+)
+
+// synthFlags は音声合成に関するフラグです。
+type synthFlags struct {
+	engineURL      string
+	speaker        string
+	speakerFile    string
+	sentencePause  time.Duration
+	paragraphPause time.Duration
+	workers        int
+	cacheDir       string
+	noCache        bool
+	resume         bool
+	retries        int
+	retryDelay     time.Duration
+	noNormalize    bool
+}
+
+func (f *synthFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.engineURL, "engine", app.EngineURL(), "VOICEVOXエンジンのURL")
+	fs.StringVar(&f.speaker, "speaker", string(app.DefaultSpeaker), "読み上げに使うスタイル(IDまたは \"話者名/スタイル名\")")
+	fs.StringVar(&f.speakerFile, "speakers", "", "話者ラベルとスタイルの対応を記述したYAMLファイル")
+	fs.DurationVar(&f.sentencePause, "sentence-pause", app.DefaultSentencePause, "文の区切り(句読点のみの行)に挿入する無音の長さ")
+	fs.DurationVar(&f.paragraphPause, "paragraph-pause", app.DefaultParagraphPause, "段落の区切り(空行)に挿入する無音の長さ")
+	// voicevox自体にそれほど処理スピードがないため、同時に実行する数は少なめにしておく
+	fs.IntVar(&f.workers, "workers", app.DefaultWorkers, "同時に音声合成する行数")
+	fs.StringVar(&f.cacheDir, "cache", "", "合成した音声のキャッシュディレクトリ(省略時はユーザーのキャッシュディレクトリ)")
+	fs.BoolVar(&f.noCache, "no-cache", false, "キャッシュを使わずに全ての行を合成する")
+	fs.BoolVar(&f.resume, "resume", false, "前回の出力を削除せず、中断した音声合成を続きから再開する")
+	retry := app.DefaultRetryPolicy()
+	fs.IntVar(&f.retries, "retries", retry.MaxAttempts-1, "エンジンへのリクエストが一時的に失敗した場合に再試行する回数")
+	fs.DurationVar(&f.retryDelay, "retry-delay", retry.InitialDelay, "最初の再試行までの待ち時間。再試行のたびに2倍にする")
+	fs.BoolVar(&f.noNormalize, "no-normalize", false, "数字や記号を読み方に変換せず、本文をそのままエンジンに送る")
+}
+
+// cache はキャッシュディレクトリを返します。キャッシュを使わない場合は空文字列を返します。
+func (f *synthFlags) cache() (string, error) {
+	if f.noCache {
+		return "", nil
+	}
+	if f.cacheDir != "" {
+		return f.cacheDir, nil
+	}
+	return app.DefaultCacheDir()
+}
+
+// voice は台本の行に話者を割り当てるための設定です。
+type voice struct {
+	speakers *app.SpeakerConfig
+	// fallbackID は話者ラベルのない行に使うスタイルIDです。
+	fallbackID int
+}
+
+// assign は lines に話者を割り当てます。
+func (v voice) assign(lines []app.ScriptLine) error {
+	return v.speakers.Assign(lines, v.fallbackID)
+}
+
+// synthesizer はフラグからエンジンのクライアントと話者の設定を準備します。
+func (f *synthFlags) synthesizer(ctx context.Context) (*app.Synthesizer, voice, error) {
+	var (
+		speakers *app.SpeakerConfig
+		err      error
+	)
+	if f.speakerFile != "" {
+		speakers, err = app.LoadSpeakerConfig(f.speakerFile)
+		if err != nil {
+			return nil, voice{}, err
+		}
+	}
+	silence := app.DefaultSilenceOptions()
+	silence.Sentence = f.sentencePause
+	silence.Paragraph = f.paragraphPause
+	cacheDir, err := f.cache()
+	if err != nil {
+		return nil, voice{}, err
+	}
+	synth, v, err := newSynthesizer(ctx, f.engineURL, app.StyleRef(f.speaker), speakers, silence, cacheDir)
+	if err != nil {
+		return nil, voice{}, err
+	}
+	synth.Retry.MaxAttempts = f.retries + 1
+	synth.Retry.InitialDelay = f.retryDelay
+	if f.noNormalize {
+		synth.Normalizer = nil
+	}
+	return synth, v, nil
+}
+
+// newSynthesizer はエンジンのクライアントを作り、話者の指定をスタイルIDに解決します。
+// エンジンのバージョンとユーザー辞書を取得し、cacheDir が空でない場合は合成した音声をキャッシュします。
+func newSynthesizer(ctx context.Context, engineURL string, speaker app.StyleRef, speakers *app.SpeakerConfig, silence app.SilenceOptions, cacheDir string) (*app.Synthesizer, voice, error) {
+	client, err := app.NewClient(engineURL, 0)
+	if err != nil {
+		return nil, voice{}, err
+	}
+	v := voice{speakers: speakers}
+	if id, ok := speaker.ID(); ok {
+		v.fallbackID = id
+	} else {
+		list, err := client.Speakers(ctx)
+		if err != nil {
+			return nil, voice{}, err
+		}
+		v.fallbackID, err = app.ResolveStyle(list, speaker)
+		if err != nil {
+			return nil, voice{}, err
+		}
+	}
+	if err := speakers.Resolve(ctx, client); err != nil {
+		return nil, voice{}, err
+	}
+
+	synth := app.NewSynthesizer(client)
+	synth.Silence = silence
+	if err := synth.LoadEngineState(ctx); err != nil {
+		return nil, voice{}, err
+	}
+	if cacheDir != "" {
+		synth.Cache, err = app.NewCache(cacheDir)
+		if err != nil {
+			return nil, voice{}, err
+		}
+	}
+	return synth, v, nil
+}
+
+// concatFlags は結合に関するフラグです。
+type concatFlags struct {
+	gap            time.Duration
+	sentenceGap    time.Duration
+	commaGap       time.Duration
+	crossfade      time.Duration
+	crossfadeCurve string
+
+	normalize      string
+	peakTarget     float64
+	loudnessTarget float64
+	truePeakLimit  float64
+
+	subtitles string
+	timing    string
+}
+
+func (f *concatFlags) register(fs *flag.FlagSet) {
+	fs.DurationVar(&f.gap, "gap", 0, "結合時に行の間に挿入する無音の長さ")
+	fs.DurationVar(&f.sentenceGap, "sentence-gap", 0, "結合時に「。」で終わる行の後に挿入する無音の長さ(0の場合は -gap)")
+	fs.DurationVar(&f.commaGap, "comma-gap", 0, "結合時に「、」で終わる行の後に挿入する無音の長さ(0の場合は -gap)")
+	fs.DurationVar(&f.crossfade, "crossfade", 0, "結合時に無音を挟まない行の間を重ねる長さ")
+	fs.StringVar(&f.crossfadeCurve, "crossfade-curve", "linear", "クロスフェードの形状 (linear, equal-power)")
+
+	fs.StringVar(&f.normalize, "normalize", "none", "結合後の音量の正規化 (none, peak, loudness)")
+	fs.Float64Var(&f.peakTarget, "peak", wav.DefaultPeakTarget, "-normalize=peak の目標のピーク(dBFS)")
+	fs.Float64Var(&f.loudnessTarget, "lufs", wav.DefaultLoudnessTarget, "-normalize=loudness の目標の統合ラウドネス(LUFS)")
+	fs.Float64Var(&f.truePeakLimit, "true-peak", wav.DefaultTruePeakLimit, "-normalize=loudness で許容する True Peak の上限(dBTP)")
+
+	fs.StringVar(&f.subtitles, "subtitles", "", "結合したWAVファイルと一緒に保存する字幕の形式。カンマ区切りで指定する (srt, vtt)")
+	fs.StringVar(&f.timing, "timing", "", "結合したWAVファイルと一緒に保存するモーラのタイミングの形式。カンマ区切りで指定する (json, csv)")
+}
+
+// options はフラグから結合の設定を作ります。
+func (f *concatFlags) options() (app.ConcatOptions, error) {
+	curve, err := wav.ParseFadeCurve(f.crossfadeCurve)
+	if err != nil {
+		return app.ConcatOptions{}, err
+	}
+	mode, err := wav.ParseNormalizeMode(f.normalize)
+	if err != nil {
+		return app.ConcatOptions{}, err
+	}
+	subtitles, err := app.ParseSubtitleFormats(f.subtitles)
+	if err != nil {
+		return app.ConcatOptions{}, err
+	}
+	timing, err := app.ParseTimingFormats(f.timing)
+	if err != nil {
+		return app.ConcatOptions{}, err
+	}
+	opts := app.ConcatOptions{
+		Convert:         true,
+		Gap:             f.gap,
+		PunctuationGaps: map[string]time.Duration{},
+		Crossfade:       f.crossfade,
+		CrossfadeCurve:  curve,
+		Normalize: wav.NormalizeOptions{
+			Mode:     mode,
+			Peak:     f.peakTarget,
+			Loudness: f.loudnessTarget,
+			TruePeak: f.truePeakLimit,
+		},
+		Subtitles: subtitles,
+		Timing:    timing,
+	}
+	if f.sentenceGap > 0 {
+		opts.PunctuationGaps["。"] = f.sentenceGap
+	}
+	if f.commaGap > 0 {
+		opts.PunctuationGaps["、"] = f.commaGap
+	}
+	return opts, nil
+}
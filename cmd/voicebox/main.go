@@ -0,0 +1,88 @@
+// voicebox はテキストから台本を作り、VOICEVOXエンジンで音声合成して1つのWAVファイルにまとめるコマンドです。
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+)
+
+// command はサブコマンドです。
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "script", summary: "テキストを整形して台本ファイルを書き出す", run: runScript},
+	{name: "synth", summary: "台本ファイルを1行ずつ音声合成してWAVファイルに保存する", run: runSynth},
+	{name: "concat", summary: "WAVファイルを結合する", run: runConcat},
+	{name: "build", summary: "テキストから台本の作成、音声合成、結合までをまとめて実行する", run: runBuild},
+	{name: "run", summary: "プロジェクトファイルに書かれた手順で build を実行する", run: runProject},
+	{name: "render", summary: "背景画像・立ち絵・音声・字幕から ffmpeg で動画を作る", run: runRender},
+	{name: "dict", summary: "読み方の辞書ファイルをエンジンのユーザー辞書に登録・書き出す", run: runDict},
+	{name: "speakers", summary: "話者・スタイルの一覧や利用規約を表示する", run: runSpeakers},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "使い方:\n  voicebox <サブコマンド> [フラグ] [引数]\n\nサブコマンド:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\n各サブコマンドのフラグは voicebox <サブコマンド> -h で表示します。\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "help" {
+		usage()
+		return
+	}
+
+	// Ctrl-C で処理中の音声合成を中断する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		err := c.run(ctx, os.Args[2:])
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "不明なサブコマンドです: %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// newFlagSet はサブコマンドのフラグを定義する FlagSet を作ります。
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "使い方:\n  voicebox %s [フラグ] %s\n\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// baseName はパスからディレクトリと拡張子を除いたファイル名を返します。
+func baseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"voicevox/app"
+)
+
+// findSubtitles は音声ファイル audio と同じ名前の字幕ファイル(.srt, .vtt の順)を探します。見つからない場合は空文字列を返します。
+func findSubtitles(audio string) string {
+	base := strings.TrimSuffix(audio, filepath.Ext(audio))
+	for _, format := range []app.SubtitleFormat{app.SubtitleSRT, app.SubtitleVTT} {
+		if _, err := os.Stat(base + format.Ext()); err == nil {
+			return base + format.Ext()
+		}
+	}
+	return ""
+}
+
+func runRender(ctx context.Context, args []string) error {
+	fs := newFlagSet("render", "WAVファイル")
+	var o app.RenderOptions
+	fs.StringVar(&o.Background, "bg", "", "背景画像(必須)")
+	fs.StringVar(&o.Character, "character", "", "右下に重ねる立ち絵の画像")
+	fs.Float64Var(&o.CharacterHeight, "character-height", app.DefaultCharacterHeight, "立ち絵の高さ(動画の高さに対する割合)")
+	fs.BoolVar(&o.CharacterLeft, "character-left", false, "立ち絵を左下に置く")
+	subtitles := fs.String("subtitles", "", "焼き込む字幕ファイル。省略した場合はWAVファイルと同じ名前の .srt か .vtt を使う")
+	noSubtitles := fs.Bool("no-subtitles", false, "字幕を入れない")
+	fs.StringVar(&o.Output, "o", "", "出力する動画ファイル(省略した場合はWAVファイルの拡張子を .mp4 にしたもの)")
+	fs.IntVar(&o.Width, "width", app.DefaultVideoWidth, "動画の幅")
+	fs.IntVar(&o.Height, "height", app.DefaultVideoHeight, "動画の高さ")
+	fs.IntVar(&o.FrameRate, "fps", app.DefaultVideoFrameRate, "動画のフレームレート")
+	fs.StringVar(&o.FFmpeg, "ffmpeg", "ffmpeg", "ffmpeg の実行ファイル")
+	dryRun := fs.Bool("dry-run", false, "ffmpeg を実行せず、実行するコマンドを表示する(実際の実行では出力先と同じディレクトリの一時ファイルに書き出してから名前を変える)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("WAVファイルを1つ指定してください")
+	}
+
+	o.Audio = fs.Arg(0)
+	if o.Output == "" {
+		o.Output = strings.TrimSuffix(o.Audio, filepath.Ext(o.Audio)) + ".mp4"
+	}
+	switch {
+	case *noSubtitles:
+	case *subtitles != "":
+		o.Subtitles = *subtitles
+	default:
+		o.Subtitles = findSubtitles(o.Audio)
+	}
+
+	if *dryRun {
+		cmd, err := o.Command()
+		if err != nil {
+			return err
+		}
+		fmt.Println(cmd)
+		return nil
+	}
+	if err := app.Render(ctx, o); err != nil {
+		return err
+	}
+	fmt.Println(o.Output)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"voicevox/app"
+)
+
+func runProject(ctx context.Context, args []string) error {
+	fs := newFlagSet("run", "プロジェクトファイル")
+	engineURL := fs.String("engine", "", "VOICEVOXエンジンのURL。指定した場合はプロジェクトファイルの engine より優先する")
+	resume := fs.Bool("resume", false, "前回の出力を削除せず、中断した音声合成を続きから再開する")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("プロジェクトファイルを1つ指定してください")
+	}
+
+	p, err := app.LoadProject(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	inputs, err := p.InputFiles()
+	if err != nil {
+		return err
+	}
+	speakers, err := p.SpeakerConfig()
+	if err != nil {
+		return err
+	}
+	dict, err := p.LoadDictionary()
+	if err != nil {
+		return err
+	}
+	engine := p.Engine
+	if *engineURL != "" {
+		engine = *engineURL
+	}
+	cacheDir, err := p.CacheDir()
+	if err != nil {
+		return err
+	}
+	synth, v, err := newSynthesizer(ctx, engine, p.Speaker, speakers, p.SilenceOptions(), cacheDir)
+	if err != nil {
+		return err
+	}
+	synth.Retry = p.RetryPolicy()
+	synth.Normalizer = p.TextNormalizer()
+	opts, err := p.ConcatOptions()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("プロジェクト %s: %d 個の入力\n", p.Name, len(inputs))
+	j := &job{
+		synth:      synth,
+		voice:      v,
+		lineLength: p.Script.Length,
+		input:      p.InputOptions(),
+		prepared:   p.Script.Prepared,
+		workers:    p.Output.Workers,
+		resume:     *resume,
+		concat:     opts,
+		dict:       dict,
+		outDir:     p.Path(p.Output.Dir),
+		tmpDir:     p.Path(p.Output.Tmp),
+		outputName: p.OutputName,
+		join:       p.Output.Join,
+	}
+	return j.run(ctx, inputs)
+}
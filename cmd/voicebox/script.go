@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"voicevox/app"
+)
+
+// scriptFlags は台本の作成に関するフラグです。
+type scriptFlags struct {
+	lineLength   int
+	format       string
+	encoding     string
+	headingPause time.Duration
+	codeBlock    string
+	pagePause    time.Duration
+}
+
+func (f *scriptFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.lineLength, "length", app.DefaultLineLength, "台本の1行の最大文字数の目安")
+	fs.StringVar(&f.format, "format", "auto", "入力ファイルの形式(auto, text, markdown, aozora)。auto は拡張子から判定する")
+	fs.StringVar(&f.encoding, "encoding", "auto", "入力ファイルの文字コード(auto, utf-8, shift_jis, euc-jp)。auto は内容から判定する")
+	fs.DurationVar(&f.headingPause, "heading-pause", app.DefaultHeadingPause, "Markdown と青空文庫形式の見出しの後に挿入する無音の長さ")
+	fs.StringVar(&f.codeBlock, "code-block", "", "Markdown のコードブロックの代わりに読み上げる文(省略した場合は読み飛ばす)")
+	fs.DurationVar(&f.pagePause, "page-pause", app.DefaultPagePause, "青空文庫形式の改ページの位置に挿入する無音の長さ")
+}
+
+// input はフラグから入力ファイルの変換の設定を作ります。
+func (f *scriptFlags) input() (app.InputOptions, error) {
+	format, err := app.ParseInputFormat(f.format)
+	if err != nil {
+		return app.InputOptions{}, err
+	}
+	encoding, err := app.ParseEncoding(f.encoding)
+	if err != nil {
+		return app.InputOptions{}, err
+	}
+	return app.InputOptions{
+		Format:   format,
+		Encoding: encoding,
+		Markdown: app.MarkdownOptions{HeadingPause: f.headingPause, CodeBlock: f.codeBlock},
+		Aozora:   app.AozoraOptions{PagePause: f.pagePause, HeadingPause: f.headingPause},
+	}, nil
+}
+
+// scriptPath は input から作った台本ファイルの保存先です。
+func scriptPath(outDir, input string) string {
+	return filepath.Join(outDir, baseName(input)+"_script.txt")
+}
+
+func runScript(ctx context.Context, args []string) error {
+	fs := newFlagSet("script", "テキストファイル...")
+	var sf scriptFlags
+	sf.register(fs)
+	outDir := fs.String("out", "out", "台本ファイルの出力ディレクトリ")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("テキストファイルを指定してください")
+	}
+	input, err := sf.input()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*outDir, os.ModePerm); err != nil {
+		return fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	}
+	for _, path := range fs.Args() {
+		segments, err := app.CreateSegmentsWith(path, &sf.lineLength, input)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		output := scriptPath(*outDir, path)
+		if err := app.WriteScriptFile(app.SegmentLines(segments), output); err != nil {
+			return err
+		}
+		fmt.Println(output)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"voicevox/app"
+)
+
+func runSpeakers(ctx context.Context, args []string) error {
+	fs := newFlagSet("speakers", `<list | resolve "話者名/スタイル名" | info 話者名>`)
+	engineURL := fs.String("engine", app.EngineURL(), "VOICEVOXエンジンのURL")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("list, resolve, info のいずれかを指定してください")
+	}
+
+	client, err := app.NewClient(*engineURL, 0)
+	if err != nil {
+		return err
+	}
+	speakers, err := client.Speakers(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\t話者\tスタイル")
+		for _, sp := range speakers {
+			for _, st := range sp.Styles {
+				fmt.Fprintf(w, "%d\t%s\t%s\n", st.ID, sp.Name, st.Name)
+			}
+		}
+		return w.Flush()
+	case "resolve":
+		if len(rest) != 1 {
+			return fmt.Errorf("resolve には \"話者名/スタイル名\" を1つ指定してください")
+		}
+		id, err := app.ResolveStyle(speakers, app.StyleRef(rest[0]))
+		if err != nil {
+			return err
+		}
+		fmt.Println(id)
+		return nil
+	case "info":
+		if len(rest) != 1 {
+			return fmt.Errorf("info には話者名を1つ指定してください")
+		}
+		for _, sp := range speakers {
+			if sp.Name != rest[0] {
+				continue
+			}
+			info, err := client.SpeakerInfo(ctx, sp.SpeakerUUID)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s (%s)\n\n%s\n", sp.Name, sp.SpeakerUUID, info.Policy)
+			return nil
+		}
+		return fmt.Errorf("%w: %q", app.ErrUnknownSpeaker, rest[0])
+	default:
+		return fmt.Errorf("不明なサブコマンドです: speakers %s", cmd)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"voicevox/app"
+)
+
+// synthesizeLines は lines の音声を dir に保存します。
+// resume が true の場合は前回の実行で保存済みの、内容が変わっていない行を除いて音声合成します。
+// 失敗した行があった場合は全ての行の音声合成を終えてから *app.SynthesisError で報告します。
+func synthesizeLines(ctx context.Context, synth *app.Synthesizer, lines []app.ScriptLine, dir string, workers int, resume bool) error {
+	p := &app.Pipeline{Synth: synth, Workers: workers, Dir: dir, Resume: resume}
+	results, err := p.Run(ctx, lines)
+	if err != nil {
+		return err
+	}
+	return app.Drain(ctx, results, func(r app.LineResult) error {
+		fmt.Printf("%s %s\n", p.LinePath(r.Index), r.Line.Text)
+		return nil
+	})
+}
+
+// printCacheStats はキャッシュを使えた行数を表示します。
+func printCacheStats(synth *app.Synthesizer) {
+	if synth.Cache != nil {
+		hits, misses := synth.Cache.Stats()
+		fmt.Printf("キャッシュ: %d 行を再利用、%d 行を合成\n", hits, misses)
+	}
+}
+
+func runSynth(ctx context.Context, args []string) error {
+	fs := newFlagSet("synth", "台本ファイル...")
+	var sf synthFlags
+	sf.register(fs)
+	outDir := fs.String("out", "tmp", "音声ファイルの出力ディレクトリ。台本ファイルごとにサブディレクトリを作ります")
+	encodingName := fs.String("encoding", "auto", "台本ファイルの文字コード(auto, utf-8, shift_jis, euc-jp)。auto は内容から判定する")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	encoding, err := app.ParseEncoding(*encodingName)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("台本ファイルを指定してください")
+	}
+
+	synth, v, err := sf.synthesizer(ctx)
+	if err != nil {
+		return err
+	}
+	for _, path := range fs.Args() {
+		lines, err := app.ReadScriptFile(path, encoding)
+		if err != nil {
+			return err
+		}
+		if err := v.assign(lines); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		dir := filepath.Join(*outDir, baseName(path))
+		if err := synthesizeLines(ctx, synth, lines, dir, sf.workers, sf.resume); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	printCacheStats(synth)
+	return nil
+}
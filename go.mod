@@ -5,11 +5,13 @@ go 1.23.2
 require (
 	github.com/ikawaha/kagome-dict/ipa v1.2.5
 	github.com/ikawaha/kagome/v2 v2.10.2
+	golang.org/x/text v0.23.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require golang.org/x/text v0.23.0 // indirect
-
 require (
 	github.com/ikawaha/kagome-dict v1.1.6 // indirect
-	github.com/samber/lo v1.50.0
+	github.com/kr/text v0.2.0 // indirect
+	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
+	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
 )
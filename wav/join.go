@@ -0,0 +1,178 @@
+package wav
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// FadeCurve はクロスフェードの形状です。
+type FadeCurve int
+
+const (
+	// FadeLinear は音量を直線的に入れ替えます。
+	FadeLinear FadeCurve = iota
+	// FadeEqualPower は合計のパワーが一定になるよう sin/cos で入れ替えます。
+	// 相関の低い音声(別の行の声)同士をつなぐ場合に中央で音量が下がりません。
+	FadeEqualPower
+)
+
+// ParseFadeCurve は "linear" または "equal-power" を FadeCurve に変換します。
+func ParseFadeCurve(s string) (FadeCurve, error) {
+	switch s {
+	case "", "linear":
+		return FadeLinear, nil
+	case "equal-power", "equalpower":
+		return FadeEqualPower, nil
+	}
+	return 0, fmt.Errorf("不明なクロスフェードの形状です: %q (linear か equal-power を指定してください)", s)
+}
+
+// gains は位置 t (0〜1) におけるフェードアウト側とフェードイン側の利得を返します。
+func (c FadeCurve) gains(t float64) (out, in float64) {
+	if c == FadeEqualPower {
+		return math.Cos(t * math.Pi / 2), math.Sin(t * math.Pi / 2)
+	}
+	return 1 - t, t
+}
+
+// SampleWriter はデコード済みのサンプルを受け取る出力先です。
+type SampleWriter interface {
+	WriteSamples(samples []float64) error
+}
+
+// WriteSamples はサンプルを Writer の形式にエンコードして追記します。
+func (w *Writer) WriteSamples(samples []float64) error {
+	data, err := encodeSamples(w.format, samples)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteSamples はサンプルをバッファの末尾に追加します。
+func (b *Buffer) WriteSamples(samples []float64) error {
+	if b.Channels > 0 && len(samples)%b.Channels != 0 {
+		return fmt.Errorf("サンプル数 %d がチャンネル数 %d の倍数ではありません", len(samples), b.Channels)
+	}
+	b.Samples = append(b.Samples, samples...)
+	return nil
+}
+
+// JoinOptions は Joiner の設定です。
+type JoinOptions struct {
+	// Crossfade は無音を挟まない境界で前後の音声を重ねる長さです。0 の場合は単純に連結します。
+	Crossfade time.Duration
+	// Curve はクロスフェードの形状です。
+	Curve FadeCurve
+}
+
+// Joiner は音声を順につなぎ、dst に書き込みます。
+// クロスフェードのために直前の音声の末尾だけを保持し、それ以外は受け取った順に書き出します。
+type Joiner struct {
+	dst        SampleWriter
+	sampleRate int
+	channels   int
+	opts       JoinOptions
+
+	// tail はまだ書き出していない直前の音声の末尾です。
+	tail []float64
+	// written はこれまでに dst に書き出したフレーム数です。
+	written int64
+	added   bool
+}
+
+// NewJoiner は sampleRate, channels の音声をつなぐ Joiner を生成します。
+func NewJoiner(dst SampleWriter, sampleRate, channels int, opts JoinOptions) *Joiner {
+	return &Joiner{dst: dst, sampleRate: sampleRate, channels: channels, opts: opts}
+}
+
+func (j *Joiner) frames(d time.Duration) int {
+	return int(int64(d) * int64(j.sampleRate) / int64(time.Second))
+}
+
+// position はフレーム数を時間に変換します。
+func (j *Joiner) position(frames int64) time.Duration {
+	return time.Duration(frames * int64(time.Second) / int64(j.sampleRate))
+}
+
+// Position は現在の出力の長さ(保持している末尾を含む)を返します。
+func (j *Joiner) Position() time.Duration {
+	return j.position(j.written + int64(len(j.tail)/j.channels))
+}
+
+// Add は buf を追加し、出力内での buf の開始位置を返します。
+// gap が正の場合は直前の音声との間に無音を挿入し、0 の場合は設定に従ってクロスフェードでつなぎます。
+func (j *Joiner) Add(buf *Buffer, gap time.Duration) (time.Duration, error) {
+	if buf.SampleRate != j.sampleRate || buf.Channels != j.channels {
+		return 0, fmt.Errorf("音声の形式(%dHz %dch)が出力(%dHz %dch)と異なります", buf.SampleRate, buf.Channels, j.sampleRate, j.channels)
+	}
+	samples := buf.Samples
+
+	if j.added && gap > 0 {
+		if err := j.flushTail(); err != nil {
+			return 0, err
+		}
+		if err := j.write(make([]float64, j.frames(gap)*j.channels)); err != nil {
+			return 0, err
+		}
+	}
+	j.added = true
+
+	// 直前の末尾と buf の先頭を重ねる
+	overlap := min(len(j.tail), len(samples)) / j.channels
+	if gap > 0 {
+		overlap = 0
+	}
+	if err := j.write(j.tail[:len(j.tail)-overlap*j.channels]); err != nil {
+		return 0, err
+	}
+	start := j.position(j.written)
+	if overlap > 0 {
+		faded := make([]float64, overlap*j.channels)
+		prev := j.tail[len(j.tail)-overlap*j.channels:]
+		for i := 0; i < overlap; i++ {
+			out, in := j.opts.Curve.gains((float64(i) + 0.5) / float64(overlap))
+			for c := 0; c < j.channels; c++ {
+				k := i*j.channels + c
+				faded[k] = prev[k]*out + samples[k]*in
+			}
+		}
+		if err := j.write(faded); err != nil {
+			return 0, err
+		}
+		samples = samples[overlap*j.channels:]
+	}
+	j.tail = nil
+
+	// 次の音声と重ねる分を残して書き出す
+	keep := min(j.frames(j.opts.Crossfade), len(samples)/j.channels) * j.channels
+	if err := j.write(samples[:len(samples)-keep]); err != nil {
+		return 0, err
+	}
+	j.tail = append([]float64(nil), samples[len(samples)-keep:]...)
+	return start, nil
+}
+
+// Close は保持している末尾を書き出します。dst はクローズしません。
+func (j *Joiner) Close() error {
+	return j.flushTail()
+}
+
+func (j *Joiner) flushTail() error {
+	err := j.write(j.tail)
+	j.tail = nil
+	return err
+}
+
+func (j *Joiner) write(samples []float64) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	if err := j.dst.WriteSamples(samples); err != nil {
+		return err
+	}
+	j.written += int64(len(samples) / j.channels)
+	return nil
+}
@@ -0,0 +1,90 @@
+package wav
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func constant(v float64, frames int) *Buffer {
+	b := &Buffer{SampleRate: 1000, Channels: 1, Samples: make([]float64, frames)}
+	for i := range b.Samples {
+		b.Samples[i] = v
+	}
+	return b
+}
+
+func TestJoinerCrossfade(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve FadeCurve
+		// mid は重なりの中央での値です
+		mid float64
+	}{
+		{name: "linear", curve: FadeLinear, mid: 0.5},
+		{name: "equal-power", curve: FadeEqualPower, mid: math.Sqrt(2) / 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := &Buffer{SampleRate: 1000, Channels: 1}
+			j := NewJoiner(dst, 1000, 1, JoinOptions{Crossfade: 10 * time.Millisecond, Curve: tt.curve})
+			if start, err := j.Add(constant(1, 100), 0); err != nil || start != 0 {
+				t.Fatalf("start = %v, err = %v", start, err)
+			}
+			start, err := j.Add(constant(0, 100), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if start != 90*time.Millisecond {
+				t.Errorf("start = %v, want 90ms", start)
+			}
+			if err := j.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if dst.Frames() != 190 {
+				t.Fatalf("frames = %d, want 190", dst.Frames())
+			}
+			if dst.Samples[89] != 1 || dst.Samples[100] != 0 {
+				t.Errorf("重なり以外の部分が変わっている: %v %v", dst.Samples[89], dst.Samples[100])
+			}
+			// 重なりの中央(5フレーム目の前後)でフェードアウト側の利得が mid 付近になる
+			mid := (dst.Samples[94] + dst.Samples[95]) / 2
+			if math.Abs(mid-tt.mid) > 0.08 {
+				t.Errorf("mid = %v, want %v", mid, tt.mid)
+			}
+			for i := 90; i < 100; i++ {
+				if dst.Samples[i] > dst.Samples[i-1] {
+					t.Errorf("フェードアウトが単調減少になっていない: %v", dst.Samples[85:101])
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestJoinerGap(t *testing.T) {
+	dst := &Buffer{SampleRate: 1000, Channels: 2}
+	j := NewJoiner(dst, 1000, 2, JoinOptions{Crossfade: 10 * time.Millisecond})
+	a := constant(1, 40).Remix(2)
+	if _, err := j.Add(a, 0); err != nil {
+		t.Fatal(err)
+	}
+	// 無音を挿入する境界ではクロスフェードしない
+	start, err := j.Add(a, 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if start != 70*time.Millisecond || dst.Duration() != 110*time.Millisecond {
+		t.Errorf("start = %v, duration = %v", start, dst.Duration())
+	}
+	if dst.Samples[2*39] != 1 || dst.Samples[2*40] != 0 || dst.Samples[2*70] != 1 {
+		t.Errorf("unexpected samples")
+	}
+
+	if _, err := j.Add(constant(1, 10), 0); err == nil {
+		t.Error("チャンネル数の異なる音声はエラーになるはず")
+	}
+}
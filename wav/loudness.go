@@ -0,0 +1,369 @@
+package wav
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// NormalizeMode は音量の正規化の方法です。
+type NormalizeMode int
+
+const (
+	// NormalizeOff は正規化を行いません。
+	NormalizeOff NormalizeMode = iota
+	// NormalizePeak はサンプルの最大値が目標のピーク(dBFS)になるよう音量を揃えます。
+	NormalizePeak
+	// NormalizeLoudness は EBU R128 (ITU-R BS.1770) の統合ラウドネスが目標(LUFS)になるよう音量を揃え、
+	// True Peak が上限を超える部分をリミッターで抑えます。
+	NormalizeLoudness
+)
+
+const (
+	// DefaultPeakTarget はピーク正規化の目標の既定値(dBFS)です。
+	DefaultPeakTarget = -1.0
+	// DefaultLoudnessTarget はラウドネス正規化の目標の既定値(LUFS)です。EBU R128 の基準値です。
+	DefaultLoudnessTarget = -23.0
+	// DefaultTruePeakLimit は True Peak の上限の既定値(dBTP)です。
+	DefaultTruePeakLimit = -1.0
+)
+
+// ParseNormalizeMode は "none", "peak", "loudness" (または "lufs") を NormalizeMode に変換します。
+func ParseNormalizeMode(s string) (NormalizeMode, error) {
+	switch s {
+	case "", "none", "off":
+		return NormalizeOff, nil
+	case "peak":
+		return NormalizePeak, nil
+	case "loudness", "lufs", "r128":
+		return NormalizeLoudness, nil
+	}
+	return 0, fmt.Errorf("不明な正規化の方法です: %q (none, peak, loudness のいずれかを指定してください)", s)
+}
+
+// NormalizeOptions は音量の正規化の設定です。
+type NormalizeOptions struct {
+	Mode NormalizeMode
+	// Peak は NormalizePeak の目標のピーク(dBFS)です。
+	Peak float64
+	// Loudness は NormalizeLoudness の目標の統合ラウドネス(LUFS)です。
+	Loudness float64
+	// TruePeak は NormalizeLoudness で許容する True Peak の上限(dBTP)です。
+	TruePeak float64
+}
+
+// DefaultNormalizeOptions は mode の既定の目標値を設定した NormalizeOptions を返します。
+func DefaultNormalizeOptions(mode NormalizeMode) NormalizeOptions {
+	return NormalizeOptions{
+		Mode:     mode,
+		Peak:     DefaultPeakTarget,
+		Loudness: DefaultLoudnessTarget,
+		TruePeak: DefaultTruePeakLimit,
+	}
+}
+
+// Normalize は opts に従って b の音量をその場で揃えます。
+// 無音のバッファなど、音量を測定できない場合は何もしません。
+func (b *Buffer) Normalize(opts NormalizeOptions) {
+	switch opts.Mode {
+	case NormalizePeak:
+		if peak := b.Peak(); peak > 0 {
+			b.Gain(opts.Peak - decibels(peak))
+		}
+	case NormalizeLoudness:
+		loudness := b.Loudness()
+		if math.IsInf(loudness, -1) {
+			return
+		}
+		b.Gain(opts.Loudness - loudness)
+		b.LimitTruePeak(opts.TruePeak)
+	}
+}
+
+// Gain は b の音量を db デシベル変えます。
+func (b *Buffer) Gain(db float64) {
+	g := amplitude(db)
+	for i := range b.Samples {
+		b.Samples[i] *= g
+	}
+}
+
+// Peak はサンプルの絶対値の最大値を返します。
+func (b *Buffer) Peak() float64 {
+	var peak float64
+	for _, v := range b.Samples {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return peak
+}
+
+// decibels は振幅の比をデシベルに変換します。
+func decibels(v float64) float64 {
+	return 20 * math.Log10(v)
+}
+
+// amplitude はデシベルを振幅の比に変換します。
+func amplitude(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// biquad は2次のIIRフィルタです。
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+// kWeighting は ITU-R BS.1770 の K 特性フィルタ(高域シェルフと高域通過)の係数を sampleRate に合わせて求めます。
+// 係数の導出は libebur128 と同じ方法です。
+func kWeighting(sampleRate int) [2]biquad {
+	fs := float64(sampleRate)
+
+	// 頭部による音響効果を模した高域シェルフ
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// 低域を除く高域通過フィルタ
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	highpass := biquad{
+		b0: 1, b1: -2, b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return [2]biquad{shelf, highpass}
+}
+
+const (
+	// loudnessBlock はラウドネスを測定するブロックの長さです。
+	loudnessBlock = 400 * time.Millisecond
+	// loudnessStep はブロックをずらす間隔です(75%の重なり)。
+	loudnessStep = 100 * time.Millisecond
+	// absoluteGate は測定から除外する無音に近いブロックのしきい値(LUFS)です。
+	absoluteGate = -70.0
+	// relativeGate は相対ゲートのしきい値(LU)です。
+	relativeGate = -10.0
+)
+
+// Loudness は ITU-R BS.1770-4 (EBU R128) の統合ラウドネスを LUFS で返します。
+// 全チャンネルの重みは1として扱います(モノラル・ステレオを想定しています)。
+// 測定できるブロックがない場合(無音など)は -Inf を返します。
+func (b *Buffer) Loudness() float64 {
+	frames := b.Frames()
+	if frames == 0 || b.SampleRate == 0 {
+		return math.Inf(-1)
+	}
+
+	// K特性フィルタを通した信号の二乗を、チャンネルを合計してフレームごとに求める
+	power := make([]float64, frames)
+	filters := kWeighting(b.SampleRate)
+	for c := 0; c < b.Channels; c++ {
+		var s [2][2]float64 // フィルタごとの状態 (直接形II転置)
+		for i := 0; i < frames; i++ {
+			v := b.Samples[i*b.Channels+c]
+			for k, f := range filters {
+				out := f.b0*v + s[k][0]
+				s[k][0] = f.b1*v - f.a1*out + s[k][1]
+				s[k][1] = f.b2*v - f.a2*out
+				v = out
+			}
+			power[i] += v * v
+		}
+	}
+
+	// 400ms のブロックごとの平均パワー。全体がブロックより短い場合は全体を1ブロックとする
+	block := max(int(int64(loudnessBlock)*int64(b.SampleRate)/int64(time.Second)), 1)
+	step := max(int(int64(loudnessStep)*int64(b.SampleRate)/int64(time.Second)), 1)
+	block = min(block, frames)
+	prefix := make([]float64, frames+1)
+	for i, p := range power {
+		prefix[i+1] = prefix[i] + p
+	}
+	var blocks []float64
+	for start := 0; start+block <= frames; start += step {
+		blocks = append(blocks, (prefix[start+block]-prefix[start])/float64(block))
+	}
+
+	gated := func(threshold float64) (float64, int) {
+		var sum float64
+		var n int
+		for _, p := range blocks {
+			if blockLoudness(p) > threshold {
+				sum += p
+				n++
+			}
+		}
+		return sum, n
+	}
+	sum, n := gated(absoluteGate)
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	sum, n = gated(blockLoudness(sum/float64(n)) + relativeGate)
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	return blockLoudness(sum / float64(n))
+}
+
+func blockLoudness(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+// truePeakTaps は True Peak を求める補間フィルタの片側のタップ数です。
+const truePeakTaps = 8
+
+// truePeakOversample は True Peak を求める際のオーバーサンプリングの倍率です。
+const truePeakOversample = 4
+
+// TruePeak はサンプル間の値も含めた最大値(4倍オーバーサンプリングによる True Peak)を返します。
+func (b *Buffer) TruePeak() float64 {
+	var peak float64
+	for _, v := range b.framePeaks() {
+		peak = math.Max(peak, v)
+	}
+	return peak
+}
+
+// framePeaks はフレームごとに、そのフレームと次のフレームの間を補間した値を含む絶対値の最大値を返します。
+func (b *Buffer) framePeaks() []float64 {
+	frames := b.Frames()
+	peaks := make([]float64, frames)
+	if frames == 0 {
+		return peaks
+	}
+
+	// 補間位置 p/truePeakOversample ごとの係数
+	var phases [truePeakOversample - 1][2 * truePeakTaps]float64
+	for p := range phases {
+		t := float64(p+1) / truePeakOversample
+		var sum float64
+		for k := range phases[p] {
+			x := float64(k-truePeakTaps+1) - t
+			phases[p][k] = sinc(x) * blackman(x/truePeakTaps)
+			sum += phases[p][k]
+		}
+		for k := range phases[p] {
+			phases[p][k] /= sum
+		}
+	}
+
+	for i := 0; i < frames; i++ {
+		for c := 0; c < b.Channels; c++ {
+			peak := math.Abs(b.Samples[i*b.Channels+c])
+			for _, h := range phases {
+				var v float64
+				for k, w := range h {
+					j := i + k - truePeakTaps + 1
+					if j < 0 || j >= frames {
+						continue
+					}
+					v += w * b.Samples[j*b.Channels+c]
+				}
+				peak = math.Max(peak, math.Abs(v))
+			}
+			peaks[i] = math.Max(peaks[i], peak)
+		}
+	}
+	return peaks
+}
+
+const (
+	// limiterLookahead はリミッターが音量を下げ始めるまでの先読みの長さです。
+	limiterLookahead = 5 * time.Millisecond
+	// limiterRelease はリミッターが音量を戻す時定数です。
+	limiterRelease = 50 * time.Millisecond
+)
+
+// LimitTruePeak は True Peak が ceiling (dBTP) を超える部分の音量を先読みリミッターで下げます。
+// 音量は先読みの区間で滑らかに下がり、limiterRelease の時定数で戻ります。
+func (b *Buffer) LimitTruePeak(ceiling float64) {
+	limit := amplitude(ceiling)
+	peaks := b.framePeaks()
+	frames := len(peaks)
+	if frames == 0 {
+		return
+	}
+
+	// 各フレームで必要な利得。補間値は前後のフレームにまたがるため両方に反映する
+	need := make([]float64, frames)
+	exceeded := false
+	for i := range need {
+		need[i] = 1
+	}
+	for i, p := range peaks {
+		if p <= limit {
+			continue
+		}
+		exceeded = true
+		g := limit / p
+		need[i] = math.Min(need[i], g)
+		if i+1 < frames {
+			need[i+1] = math.Min(need[i+1], g)
+		}
+	}
+	if !exceeded {
+		return
+	}
+
+	lookahead := max(int(int64(limiterLookahead)*int64(b.SampleRate)/int64(time.Second)), 1)
+	release := 1 - math.Exp(-1/(limiterRelease.Seconds()*float64(b.SampleRate)))
+
+	// 先読み区間 [i, i+lookahead) の最小値を取り、徐々に 1 へ戻す
+	gain := slidingMin(need, lookahead)
+	for i := 1; i < frames; i++ {
+		gain[i] = math.Min(gain[i], gain[i-1]+(1-gain[i-1])*release)
+	}
+
+	// 直前の lookahead フレームで平均して滑らかにする(先頭より前は利得1として扱う)。
+	// ピークのフレームを含む区間の最小値だけを平均するので、ピークでの利得は必要な値以下になる
+	smoothed := make([]float64, frames)
+	sum := float64(lookahead)
+	for i, g := range gain {
+		sum += g
+		if i >= lookahead {
+			sum -= gain[i-lookahead]
+		} else {
+			sum--
+		}
+		smoothed[i] = sum / float64(lookahead)
+	}
+
+	for i, g := range smoothed {
+		for c := 0; c < b.Channels; c++ {
+			b.Samples[i*b.Channels+c] *= g
+		}
+	}
+
+	// 利得の変化による補間値のわずかな超過は全体の音量で吸収する
+	if peak := b.TruePeak(); peak > limit {
+		b.Gain(decibels(limit / peak))
+	}
+}
+
+// slidingMin は各 i について v[i:i+n] の最小値を求めます。
+func slidingMin(v []float64, n int) []float64 {
+	out := make([]float64, len(v))
+	// 値が増加する順に並んだインデックスの列。先頭が区間の最小値
+	var queue []int
+	for i := len(v) - 1; i >= 0; i-- {
+		for len(queue) > 0 && v[queue[len(queue)-1]] >= v[i] {
+			queue = queue[:len(queue)-1]
+		}
+		queue = append(queue, i)
+		if queue[0] >= i+n {
+			queue = queue[1:]
+		}
+		out[i] = v[queue[0]]
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package wav
+
+import (
+	"math"
+	"testing"
+)
+
+// sine は周波数 freq、振幅 amp の正弦波を seconds 秒分生成します。
+func sine(rate, channels int, freq, amp, seconds float64) *Buffer {
+	frames := int(float64(rate) * seconds)
+	b := &Buffer{SampleRate: rate, Channels: channels, Samples: make([]float64, frames*channels)}
+	for i := 0; i < frames; i++ {
+		v := amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))
+		for c := 0; c < channels; c++ {
+			b.Samples[i*channels+c] = v
+		}
+	}
+	return b
+}
+
+func TestLoudness(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  *Buffer
+		want float64
+	}{
+		// 1kHz の正弦波は K 特性でほぼ減衰しないため、-20dBFS のモノラルは約 -23 LUFS になる
+		{name: "mono -20dBFS", buf: sine(48000, 1, 1000, amplitude(-20), 5), want: -23.01},
+		{name: "stereo -20dBFS", buf: sine(48000, 2, 1000, amplitude(-20), 5), want: -20.0},
+		{name: "24kHz", buf: sine(24000, 1, 1000, amplitude(-20), 5), want: -23.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.buf.Loudness(); math.Abs(got-tt.want) > 0.1 {
+				t.Errorf("loudness = %.2f LUFS, want %.2f", got, tt.want)
+			}
+		})
+	}
+
+	// 無音の区間はゲートで除外され、統合ラウドネスをほとんど下げない(境界をまたぐブロックの分だけ下がる)
+	b := sine(48000, 1, 1000, amplitude(-20), 5)
+	b.Samples = append(b.Samples, make([]float64, 48000*10)...)
+	if got := b.Loudness(); math.Abs(got+23.01) > 0.2 {
+		t.Errorf("with silence: loudness = %.2f LUFS", got)
+	}
+
+	if got := (&Buffer{SampleRate: 48000, Channels: 1, Samples: make([]float64, 48000)}).Loudness(); !math.IsInf(got, -1) {
+		t.Errorf("silence: loudness = %v", got)
+	}
+}
+
+func TestTruePeak(t *testing.T) {
+	// fs/4 の正弦波を位相45度でサンプリングするとサンプルの最大値は振幅の 1/√2 になるが、True Peak は振幅になる
+	b := &Buffer{SampleRate: 48000, Channels: 1}
+	for i := 0; i < 4800; i++ {
+		b.Samples = append(b.Samples, math.Sin(math.Pi/2*float64(i)+math.Pi/4))
+	}
+	if got := b.Peak(); math.Abs(got-math.Sqrt2/2) > 1e-9 {
+		t.Errorf("peak = %v", got)
+	}
+	if got := b.TruePeak(); math.Abs(got-1) > 0.02 {
+		t.Errorf("true peak = %v, want 1", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Run("peak", func(t *testing.T) {
+		b := sine(24000, 1, 440, 0.25, 1)
+		b.Normalize(DefaultNormalizeOptions(NormalizePeak))
+		if got := decibels(b.Peak()); math.Abs(got-DefaultPeakTarget) > 0.01 {
+			t.Errorf("peak = %.2f dBFS", got)
+		}
+	})
+
+	t.Run("loudness", func(t *testing.T) {
+		opts := DefaultNormalizeOptions(NormalizeLoudness)
+		opts.Loudness = -16
+		b := sine(24000, 1, 1000, 0.05, 5)
+		b.Normalize(opts)
+		if got := b.Loudness(); math.Abs(got+16) > 0.1 {
+			t.Errorf("loudness = %.2f LUFS", got)
+		}
+	})
+
+	t.Run("true peak limit", func(t *testing.T) {
+		// 目標のラウドネスに合わせると上限を超える音量は、リミッターで上限以下に抑える
+		opts := DefaultNormalizeOptions(NormalizeLoudness)
+		opts.Loudness = -6
+		b := sine(24000, 2, 1000, 0.1, 3)
+		b.Normalize(opts)
+		if got := decibels(b.TruePeak()); got > opts.TruePeak+1e-6 {
+			t.Errorf("true peak = %.3f dBTP, want <= %v", got, opts.TruePeak)
+		}
+		if got := b.Loudness(); got < -8 {
+			t.Errorf("loudness = %.2f LUFS, limited too much", got)
+		}
+	})
+
+	t.Run("silence", func(t *testing.T) {
+		b := &Buffer{SampleRate: 24000, Channels: 1, Samples: make([]float64, 24000)}
+		b.Normalize(DefaultNormalizeOptions(NormalizeLoudness))
+		if b.Peak() != 0 {
+			t.Error("無音は変更しないはず")
+		}
+	})
+}
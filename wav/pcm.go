@@ -0,0 +1,180 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Buffer はデコード済みのPCMサンプルです。
+// Samples は -1〜1 の値をチャンネルごとに交互(インターリーブ)に並べたものです。
+type Buffer struct {
+	SampleRate int
+	Channels   int
+	Samples    []float64
+}
+
+// Frames はサンプルフレーム数(1チャンネルあたりのサンプル数)を返します。
+func (b *Buffer) Frames() int {
+	if b.Channels == 0 {
+		return 0
+	}
+	return len(b.Samples) / b.Channels
+}
+
+// Duration は再生時間を返します。
+func (b *Buffer) Duration() time.Duration {
+	if b.SampleRate == 0 {
+		return 0
+	}
+	return time.Duration(int64(b.Frames()) * int64(time.Second) / int64(b.SampleRate))
+}
+
+// Buffer はPCMデータをデコードします。
+func (f *File) Buffer() (*Buffer, error) {
+	samples, err := decodeSamples(f.Format, f.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &Buffer{SampleRate: int(f.Format.SampleRate), Channels: int(f.Format.Channels), Samples: samples}, nil
+}
+
+// File は b を format のビット深度でエンコードします。
+// format のサンプルレートとチャンネル数は b と一致している必要があります。
+func (b *Buffer) File(format Format) (*File, error) {
+	if int(format.SampleRate) != b.SampleRate || int(format.Channels) != b.Channels {
+		return nil, fmt.Errorf("バッファ(%dHz %dch)と出力形式(%s)が一致しません", b.SampleRate, b.Channels, format)
+	}
+	data, err := encodeSamples(format, b.Samples)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Format: format, Data: data}, nil
+}
+
+// Convert は f を target の形式に変換します。
+// サンプルレートの変換、チャンネル数の変換(ダウンミックス/複製)、ビット深度の変換を行います。
+func Convert(f *File, target Format) (*File, error) {
+	if err := target.validate(); err != nil {
+		return nil, err
+	}
+	if f.Format == target {
+		return f, nil
+	}
+	b, err := f.Buffer()
+	if err != nil {
+		return nil, err
+	}
+	b = b.Remix(int(target.Channels)).Resample(int(target.SampleRate))
+	return b.File(target)
+}
+
+// Remix はチャンネル数を channels に変換した新しいバッファを返します。
+// モノラルへの変換は全チャンネルの平均、モノラルからの変換は全チャンネルへの複製、
+// それ以外は不足するチャンネルを先頭から繰り返して埋めます。
+func (b *Buffer) Remix(channels int) *Buffer {
+	if channels == b.Channels {
+		return b
+	}
+	frames := b.Frames()
+	out := &Buffer{SampleRate: b.SampleRate, Channels: channels, Samples: make([]float64, frames*channels)}
+	for i := 0; i < frames; i++ {
+		in := b.Samples[i*b.Channels : (i+1)*b.Channels]
+		dst := out.Samples[i*channels : (i+1)*channels]
+		switch {
+		case channels == 1:
+			var sum float64
+			for _, v := range in {
+				sum += v
+			}
+			dst[0] = sum / float64(len(in))
+		default:
+			for c := range dst {
+				dst[c] = in[c%len(in)]
+			}
+		}
+	}
+	return out
+}
+
+func decodeSamples(f Format, data []byte) ([]float64, error) {
+	width := int(f.BitsPerSample) / 8
+	n := len(data) / width
+	out := make([]float64, n)
+	switch {
+	case f.AudioFormat == FormatIEEEFloat && width == 4:
+		for i := range out {
+			out[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+		}
+	case f.AudioFormat == FormatIEEEFloat && width == 8:
+		for i := range out {
+			out[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+		}
+	case f.AudioFormat == FormatPCM && width == 1:
+		// 8bitは符号なし
+		for i := range out {
+			out[i] = (float64(data[i]) - 128) / 128
+		}
+	case f.AudioFormat == FormatPCM && width == 2:
+		for i := range out {
+			out[i] = float64(int16(binary.LittleEndian.Uint16(data[i*2:]))) / (1 << 15)
+		}
+	case f.AudioFormat == FormatPCM && width == 3:
+		for i := range out {
+			p := data[i*3:]
+			v := int32(uint32(p[0])<<8|uint32(p[1])<<16|uint32(p[2])<<24) >> 8
+			out[i] = float64(v) / (1 << 23)
+		}
+	case f.AudioFormat == FormatPCM && width == 4:
+		for i := range out {
+			out[i] = float64(int32(binary.LittleEndian.Uint32(data[i*4:]))) / (1 << 31)
+		}
+	default:
+		return nil, fmt.Errorf("対応していないサンプル形式です: %s", f)
+	}
+	return out, nil
+}
+
+func encodeSamples(f Format, samples []float64) ([]byte, error) {
+	width := int(f.BitsPerSample) / 8
+	data := make([]byte, len(samples)*width)
+	switch {
+	case f.AudioFormat == FormatIEEEFloat && width == 4:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(v)))
+		}
+	case f.AudioFormat == FormatIEEEFloat && width == 8:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint64(data[i*8:], math.Float64bits(v))
+		}
+	case f.AudioFormat == FormatPCM && width == 1:
+		for i, v := range samples {
+			data[i] = uint8(quantize(v, 7) + 128)
+		}
+	case f.AudioFormat == FormatPCM && width == 2:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(quantize(v, 15))))
+		}
+	case f.AudioFormat == FormatPCM && width == 3:
+		for i, v := range samples {
+			q := uint32(int32(quantize(v, 23)))
+			data[i*3], data[i*3+1], data[i*3+2] = byte(q), byte(q>>8), byte(q>>16)
+		}
+	case f.AudioFormat == FormatPCM && width == 4:
+		for i, v := range samples {
+			binary.LittleEndian.PutUint32(data[i*4:], uint32(int32(quantize(v, 31))))
+		}
+	default:
+		return nil, fmt.Errorf("対応していないサンプル形式です: %s", f)
+	}
+	return data, nil
+}
+
+// quantize は -1〜1 の値を bits ビットの符号付き整数の範囲に丸めます。範囲外の値はクリップします。
+func quantize(v float64, bits uint) int64 {
+	max := float64(int64(1)<<bits - 1)
+	min := -float64(int64(1) << bits)
+	q := math.Round(v * float64(int64(1)<<bits))
+	return int64(math.Max(min, math.Min(max, q)))
+}
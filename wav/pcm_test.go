@@ -0,0 +1,85 @@
+package wav
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSampleCodecRoundTrip(t *testing.T) {
+	samples := []float64{0, 0.5, -0.5, 0.25, -1}
+	formats := []Format{
+		{AudioFormat: FormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: 8},
+		{AudioFormat: FormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: 16},
+		{AudioFormat: FormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: 24},
+		{AudioFormat: FormatPCM, Channels: 1, SampleRate: 8000, BitsPerSample: 32},
+		{AudioFormat: FormatIEEEFloat, Channels: 1, SampleRate: 8000, BitsPerSample: 32},
+	}
+	for _, f := range formats {
+		t.Run(f.String(), func(t *testing.T) {
+			file, err := (&Buffer{SampleRate: 8000, Channels: 1, Samples: samples}).File(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := file.Buffer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tolerance := 1 / math.Pow(2, float64(f.BitsPerSample-1))
+			for i, want := range samples {
+				if math.Abs(b.Samples[i]-want) > tolerance {
+					t.Errorf("sample %d = %v, want %v", i, b.Samples[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	// 44.1kHz ステレオ 24bit の 440Hz 正弦波 (0.1秒)
+	src := &Buffer{SampleRate: 44100, Channels: 2}
+	for i := 0; i < 4410; i++ {
+		v := 0.5 * math.Sin(2*math.Pi*440*float64(i)/44100)
+		src.Samples = append(src.Samples, v, v)
+	}
+	in, err := src.File(Format{AudioFormat: FormatPCM, Channels: 2, SampleRate: 44100, BitsPerSample: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Convert(in, mono16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Format != mono16 {
+		t.Fatalf("format = %v", out.Format)
+	}
+	b, err := out.Buffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Frames() != 2400 {
+		t.Errorf("frames = %d, want 2400", b.Frames())
+	}
+	// 中央付近の波形が元の正弦波と一致すること
+	for i := 100; i < 2300; i++ {
+		want := 0.5 * math.Sin(2*math.Pi*440*float64(i)/24000)
+		if math.Abs(b.Samples[i]-want) > 0.01 {
+			t.Fatalf("sample %d = %v, want %v", i, b.Samples[i], want)
+		}
+	}
+	if d := out.Duration(); d != in.Duration() {
+		t.Errorf("duration = %v, want %v", d, in.Duration())
+	}
+}
+
+func TestRemix(t *testing.T) {
+	stereo := &Buffer{SampleRate: 8000, Channels: 2, Samples: []float64{1, 0, 0.5, 0.5}}
+	mono := stereo.Remix(1)
+	if mono.Channels != 1 || mono.Samples[0] != 0.5 || mono.Samples[1] != 0.5 {
+		t.Errorf("mono = %+v", mono)
+	}
+	back := mono.Remix(2)
+	if back.Channels != 2 || len(back.Samples) != 4 || back.Samples[0] != back.Samples[1] {
+		t.Errorf("stereo = %+v", back)
+	}
+}
@@ -0,0 +1,66 @@
+package wav
+
+import "math"
+
+// resampleTaps は窓関数付きsinc補間の片側のタップ数です。
+const resampleTaps = 16
+
+// Resample はサンプルレートを rate に変換した新しいバッファを返します。
+// 窓関数(Blackman)付きsinc補間を使い、ダウンサンプリング時は変換後のナイキスト周波数で帯域制限します。
+func (b *Buffer) Resample(rate int) *Buffer {
+	if rate == b.SampleRate || b.Frames() == 0 {
+		return &Buffer{SampleRate: rate, Channels: b.Channels, Samples: b.Samples}
+	}
+	ratio := float64(rate) / float64(b.SampleRate)
+	inFrames := b.Frames()
+	outFrames := int(math.Round(float64(inFrames) * ratio))
+
+	// ダウンサンプリングではフィルタの遮断周波数を下げ、その分窓を広げる
+	cutoff := math.Min(1, ratio)
+	half := float64(resampleTaps) / cutoff
+
+	out := &Buffer{SampleRate: rate, Channels: b.Channels, Samples: make([]float64, outFrames*b.Channels)}
+	weights := make([]float64, 0, int(2*half)+2)
+	for i := 0; i < outFrames; i++ {
+		pos := float64(i) / ratio
+		lo := max(int(math.Ceil(pos-half)), 0)
+		hi := min(int(math.Floor(pos+half)), inFrames-1)
+
+		weights = weights[:0]
+		var wsum float64
+		for j := lo; j <= hi; j++ {
+			x := float64(j) - pos
+			w := sinc(cutoff*x) * blackman(x/half)
+			weights = append(weights, w)
+			wsum += w
+		}
+		if wsum == 0 {
+			continue
+		}
+		for c := 0; c < b.Channels; c++ {
+			var sum float64
+			for k, w := range weights {
+				sum += w * b.Samples[(lo+k)*b.Channels+c]
+			}
+			// 係数の合計で割り、端でタップが欠けた場合も直流成分の利得を1に保つ
+			out.Samples[i*b.Channels+c] = sum / wsum
+		}
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman は -1〜1 の範囲で定義されるBlackman窓です。
+func blackman(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	t := math.Pi * (x + 1)
+	return 0.42 - 0.5*math.Cos(t) + 0.08*math.Cos(2*t)
+}
@@ -0,0 +1,16 @@
+package wav
+
+import "time"
+
+// Silence は format 形式で長さ d の無音を生成します。
+func Silence(format Format, d time.Duration) *File {
+	frames := int64(d) * int64(format.SampleRate) / int64(time.Second)
+	data := make([]byte, frames*int64(format.BlockAlign()))
+	if format.AudioFormat == FormatPCM && format.BitsPerSample == 8 {
+		// 8bit PCM は符号なしで、128 が無音
+		for i := range data {
+			data[i] = 128
+		}
+	}
+	return &File{Format: format, Data: data}
+}
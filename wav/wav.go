@@ -0,0 +1,310 @@
+// Package wav はRIFF/WAVEファイルの読み書きを行います。
+package wav
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// AudioFormat の値
+const (
+	FormatPCM        = 0x0001
+	FormatIEEEFloat  = 0x0003
+	FormatExtensible = 0xFFFE
+)
+
+// ErrNotWAVE は入力がRIFF/WAVE形式でない場合に返されます。
+var ErrNotWAVE = errors.New("RIFF/WAVE形式ではありません")
+
+// Format は fmt チャンクに記録された音声の形式です。
+type Format struct {
+	// AudioFormat は FormatPCM か FormatIEEEFloat です。
+	// WAVE_FORMAT_EXTENSIBLE のファイルはサブフォーマットの値に読み替えます。
+	AudioFormat   uint16
+	Channels      uint16
+	SampleRate    uint32
+	BitsPerSample uint16
+}
+
+// BlockAlign は1サンプルフレーム(全チャンネル分)のバイト数です。
+func (f Format) BlockAlign() int {
+	return int(f.Channels) * int(f.BitsPerSample) / 8
+}
+
+// ByteRate は1秒あたりのバイト数です。
+func (f Format) ByteRate() int {
+	return int(f.SampleRate) * f.BlockAlign()
+}
+
+// Duration は n バイトのPCMデータの再生時間を返します。
+func (f Format) Duration(n int) time.Duration {
+	if f.ByteRate() == 0 {
+		return 0
+	}
+	return time.Duration(int64(n) * int64(time.Second) / int64(f.ByteRate()))
+}
+
+func (f Format) String() string {
+	kind := "PCM"
+	if f.AudioFormat == FormatIEEEFloat {
+		kind = "float"
+	}
+	return fmt.Sprintf("%dHz %dch %dbit %s", f.SampleRate, f.Channels, f.BitsPerSample, kind)
+}
+
+func (f Format) validate() error {
+	if f.AudioFormat != FormatPCM && f.AudioFormat != FormatIEEEFloat {
+		return fmt.Errorf("対応していない音声形式です: 0x%04x", f.AudioFormat)
+	}
+	if f.Channels == 0 || f.SampleRate == 0 || f.BitsPerSample == 0 || f.BitsPerSample%8 != 0 {
+		return fmt.Errorf("fmtチャンクが不正です: %s", f)
+	}
+	return nil
+}
+
+// Chunk は fmt と data 以外のチャンク(LIST, fact など)です。
+type Chunk struct {
+	ID   string
+	Data []byte
+}
+
+// File はWAVファイルの内容です。
+type File struct {
+	Format Format
+	// Data は data チャンクのPCMデータです。
+	Data []byte
+	// Chunks は fmt と data 以外のチャンクで、ファイル内の順序を保持します。
+	Chunks []Chunk
+}
+
+// Duration は再生時間を返します。
+func (f *File) Duration() time.Duration {
+	return f.Format.Duration(len(f.Data))
+}
+
+// Decode は r からWAVファイルを読み込みます。
+func Decode(r io.Reader) (*File, error) {
+	br := bufio.NewReader(r)
+	var hdr [12]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotWAVE, err)
+	}
+	if string(hdr[0:4]) != "RIFF" || string(hdr[8:12]) != "WAVE" {
+		return nil, ErrNotWAVE
+	}
+
+	var f File
+	var haveFmt, haveData bool
+	for {
+		var ch [8]byte
+		_, err := io.ReadFull(br, ch[:])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("チャンクヘッダーの読み込みに失敗しました: %w", err)
+		}
+		id := string(ch[0:4])
+		size := binary.LittleEndian.Uint32(ch[4:8])
+
+		var body []byte
+		if id == "data" && (size == 0 || size == 0xFFFFFFFF) {
+			// ストリーミング出力などでサイズが確定していない場合は、残りのうち後ろに続くチャンクの前までをデータとする
+			var rest []byte
+			if rest, err = io.ReadAll(br); err != nil {
+				return nil, fmt.Errorf("%sチャンクの読み込みに失敗しました: %w", id, err)
+			}
+			end, next := trailingChunks(rest, max(f.Format.BlockAlign(), 1))
+			body = rest[:end]
+			br.Reset(bytes.NewReader(rest[next:]))
+		} else {
+			// サイズは壊れたヘッダーでは信用できないので、先に確保せず読めた分だけバッファを伸ばす
+			var buf bytes.Buffer
+			if _, err = io.CopyN(&buf, br, int64(size)); err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%sチャンクの読み込みに失敗しました: %w", id, err)
+			}
+			body = buf.Bytes()
+			if size%2 == 1 {
+				// チャンクは2バイト境界に揃えられている
+				br.ReadByte()
+			}
+		}
+
+		switch id {
+		case "fmt ":
+			f.Format, err = parseFormat(body)
+			if err != nil {
+				return nil, err
+			}
+			haveFmt = true
+		case "data":
+			if haveData {
+				return nil, fmt.Errorf("dataチャンクが複数あります")
+			}
+			f.Data = body
+			haveData = true
+		default:
+			f.Chunks = append(f.Chunks, Chunk{ID: id, Data: body})
+		}
+	}
+	if !haveFmt {
+		return nil, fmt.Errorf("fmtチャンクがありません")
+	}
+	if !haveData {
+		return nil, fmt.Errorf("dataチャンクがありません")
+	}
+	if n := f.Format.BlockAlign(); len(f.Data)%n != 0 {
+		f.Data = f.Data[:len(f.Data)-len(f.Data)%n]
+	}
+	return &f, nil
+}
+
+// trailingChunks はサイズが確定していない data チャンクの残り b から、データの終わり end と
+// 後ろに続くチャンク(LIST, id3 など)の始まる位置 next を返します。
+// step(フレームのバイト数)ごとの位置から、チャンクのヘッダーがちょうど末尾まで続く最初の位置を探します。
+// 後ろにチャンクがない場合はどちらも len(b) です。
+func trailingChunks(b []byte, step int) (end, next int) {
+	for p := 0; p+8 <= len(b); p += step {
+		if isChunkChain(b[p:]) {
+			return p, p
+		}
+		if p%2 == 1 && isChunkChain(b[p+1:]) {
+			// 奇数長のデータの後の詰め物
+			return p, p + 1
+		}
+	}
+	return len(b), len(b)
+}
+
+// isChunkChain は b がチャンクの並びで、最後のチャンクがちょうど末尾で終わるかを返します。
+func isChunkChain(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for len(b) > 0 {
+		if len(b) < 8 || !isChunkID(b[:4]) {
+			return false
+		}
+		size := int64(binary.LittleEndian.Uint32(b[4:8]))
+		n := 8 + size + size%2
+		if n > int64(len(b)) {
+			// 最後のチャンクは2バイト境界の詰め物を省略していてもよい
+			return 8+size == int64(len(b))
+		}
+		b = b[n:]
+	}
+	return true
+}
+
+// isChunkID は id がチャンクのIDとして使える印字可能なASCII文字の列かを返します。
+func isChunkID(id []byte) bool {
+	if id[0] == ' ' {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x20 || c > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+func parseFormat(b []byte) (Format, error) {
+	if len(b) < 16 {
+		return Format{}, fmt.Errorf("fmtチャンクが短すぎます: %dバイト", len(b))
+	}
+	f := Format{
+		AudioFormat:   binary.LittleEndian.Uint16(b[0:2]),
+		Channels:      binary.LittleEndian.Uint16(b[2:4]),
+		SampleRate:    binary.LittleEndian.Uint32(b[4:8]),
+		BitsPerSample: binary.LittleEndian.Uint16(b[14:16]),
+	}
+	if f.AudioFormat == FormatExtensible {
+		// cbSize(2) validBits(2) channelMask(4) subFormat GUID(16)
+		if len(b) < 40 {
+			return Format{}, fmt.Errorf("WAVE_FORMAT_EXTENSIBLE のfmtチャンクが短すぎます")
+		}
+		f.AudioFormat = binary.LittleEndian.Uint16(b[24:26])
+	}
+	if err := f.validate(); err != nil {
+		return Format{}, err
+	}
+	return f, nil
+}
+
+// ReadFile はWAVファイルを読み込みます。
+func ReadFile(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f, err := Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Encode は f を w に書き込みます。fmt、その他のチャンク、data の順に出力します。
+func Encode(w io.Writer, f *File) error {
+	if err := f.Format.validate(); err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	writeChunk(&body, "fmt ", formatChunk(f.Format))
+	for _, c := range f.Chunks {
+		writeChunk(&body, c.ID, c.Data)
+	}
+	writeChunk(&body, "data", f.Data)
+
+	var hdr [8]byte
+	copy(hdr[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(body.Len()))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := body.WriteTo(w)
+	return err
+}
+
+// WriteFile は f をWAVファイルとして path に保存します。
+func WriteFile(path string, f *File) error {
+	var buf bytes.Buffer
+	if err := Encode(&buf, f); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func formatChunk(f Format) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint16(b[0:2], f.AudioFormat)
+	binary.LittleEndian.PutUint16(b[2:4], f.Channels)
+	binary.LittleEndian.PutUint32(b[4:8], f.SampleRate)
+	binary.LittleEndian.PutUint32(b[8:12], uint32(f.ByteRate()))
+	binary.LittleEndian.PutUint16(b[12:14], uint16(f.BlockAlign()))
+	binary.LittleEndian.PutUint16(b[14:16], f.BitsPerSample)
+	return b
+}
+
+func writeChunk(buf *bytes.Buffer, id string, data []byte) {
+	var hdr [8]byte
+	copy(hdr[0:4], id)
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(len(data)))
+	buf.Write(hdr[:])
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
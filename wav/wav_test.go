@@ -0,0 +1,218 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var mono16 = Format{AudioFormat: FormatPCM, Channels: 1, SampleRate: 24000, BitsPerSample: 16}
+
+// rawWAV はチャンクを指定した順に並べたWAVファイルのバイト列を作ります。
+func rawWAV(chunks ...Chunk) []byte {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	for _, c := range chunks {
+		writeChunk(&body, c.ID, c.Data)
+	}
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(body.Len()))
+	body.WriteTo(&b)
+	return b.Bytes()
+}
+
+func TestDecodeWithExtraChunks(t *testing.T) {
+	data := []byte{1, 0, 2, 0, 3, 0}
+	list := []byte("INFOISFT\x05\x00\x00\x00test\x00") // 奇数長のチャンク
+	raw := rawWAV(
+		Chunk{ID: "fmt ", Data: formatChunk(mono16)},
+		Chunk{ID: "LIST", Data: list},
+		Chunk{ID: "data", Data: data},
+	)
+	f, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Format != mono16 {
+		t.Errorf("format = %v", f.Format)
+	}
+	if !bytes.Equal(f.Data, data) {
+		t.Errorf("data = %v", f.Data)
+	}
+	if len(f.Chunks) != 1 || f.Chunks[0].ID != "LIST" || !bytes.Equal(f.Chunks[0].Data, list) {
+		t.Errorf("chunks = %+v", f.Chunks)
+	}
+
+	// 書き出して読み直しても同じ内容になる
+	var buf bytes.Buffer
+	if err := Encode(&buf, f); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f, g) {
+		t.Errorf("round trip mismatch:\n%+v\n%+v", f, g)
+	}
+}
+
+func TestDecodeExtensible(t *testing.T) {
+	ext := make([]byte, 40)
+	copy(ext, formatChunk(Format{AudioFormat: FormatExtensible, Channels: 2, SampleRate: 48000, BitsPerSample: 24}))
+	binary.LittleEndian.PutUint16(ext[16:18], 22)
+	binary.LittleEndian.PutUint16(ext[24:26], FormatPCM)
+	raw := rawWAV(Chunk{ID: "fmt ", Data: ext}, Chunk{ID: "data", Data: make([]byte, 12)})
+
+	f, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Format{AudioFormat: FormatPCM, Channels: 2, SampleRate: 48000, BitsPerSample: 24}
+	if f.Format != want || len(f.Data) != 12 {
+		t.Errorf("got %v (%d bytes)", f.Format, len(f.Data))
+	}
+}
+
+func TestDecodeUnknownDataSize(t *testing.T) {
+	data := []byte{1, 0, 2, 0, 3, 0}
+	list := []byte("INFOISFT\x05\x00\x00\x00test\x00")
+	tests := []struct {
+		name   string
+		size   uint32
+		chunks []Chunk
+	}{
+		{name: "サイズ0", size: 0},
+		{name: "サイズ0xFFFFFFFF", size: 0xFFFFFFFF},
+		{name: "後ろにLISTチャンク", size: 0, chunks: []Chunk{{ID: "LIST", Data: list}}},
+		{name: "後ろに複数のチャンク", size: 0xFFFFFFFF, chunks: []Chunk{{ID: "LIST", Data: list}, {ID: "id3 ", Data: []byte("ID3\x04")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := rawWAV(append([]Chunk{{ID: "fmt ", Data: formatChunk(mono16)}, {ID: "data", Data: data}}, tt.chunks...)...)
+			// data チャンクのサイズを書き換える
+			i := bytes.Index(raw, []byte("data"))
+			binary.LittleEndian.PutUint32(raw[i+4:], tt.size)
+
+			f, err := Decode(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(f.Data, data) {
+				t.Errorf("data = %v, want %v", f.Data, data)
+			}
+			if !reflect.DeepEqual(f.Chunks, tt.chunks) {
+				t.Errorf("chunks = %+v, want %+v", f.Chunks, tt.chunks)
+			}
+		})
+	}
+}
+
+func TestDecodeShortChunk(t *testing.T) {
+	tests := []struct {
+		name string
+		size uint32
+	}{
+		{name: "ヘッダーより短いデータ", size: 8},
+		{name: "不正に大きいサイズ", size: 0xFFFFFFF0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := rawWAV(Chunk{ID: "fmt ", Data: formatChunk(mono16)}, Chunk{ID: "data", Data: []byte{1, 0, 2, 0}})
+			i := bytes.Index(raw, []byte("data"))
+			binary.LittleEndian.PutUint32(raw[i+4:], tt.size)
+			if _, err := Decode(bytes.NewReader(raw)); !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{name: "RIFFではない", raw: []byte("not a wave file")},
+		{name: "fmtチャンクなし", raw: rawWAV(Chunk{ID: "data", Data: []byte{0, 0}})},
+		{name: "dataチャンクなし", raw: rawWAV(Chunk{ID: "fmt ", Data: formatChunk(mono16)})},
+		{name: "非対応の形式", raw: rawWAV(Chunk{ID: "fmt ", Data: formatChunk(Format{AudioFormat: 0x55, Channels: 1, SampleRate: 8000, BitsPerSample: 16})}, Chunk{ID: "data"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(tt.raw)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestReadAssets(t *testing.T) {
+	for _, name := range []string{"silent_N.wav", "silent_L.wav"} {
+		f, err := ReadFile(filepath.Join("..", "asset", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Format.SampleRate != 44100 || f.Format.Channels != 1 || f.Format.BitsPerSample != 16 {
+			t.Errorf("%s: format = %v", name, f.Format)
+		}
+		if f.Duration() <= 0 {
+			t.Errorf("%s: duration = %v", name, f.Duration())
+		}
+	}
+}
+
+func TestConcat(t *testing.T) {
+	a := &File{Format: mono16, Data: []byte{1, 0, 2, 0}}
+	b := &File{Format: mono16, Data: []byte{3, 0}, Chunks: []Chunk{{ID: "LIST", Data: []byte("INFO")}}}
+
+	path := filepath.Join(t.TempDir(), "out.wav")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Concat(out, a, b); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != mono16 || !bytes.Equal(got.Data, []byte{1, 0, 2, 0, 3, 0}) {
+		t.Errorf("got %v %v", got.Format, got.Data)
+	}
+	if st, _ := os.Stat(path); st.Size() != headerSize+6 {
+		t.Errorf("size = %d", st.Size())
+	}
+
+	c := &File{Format: Format{AudioFormat: FormatPCM, Channels: 1, SampleRate: 44100, BitsPerSample: 16}, Data: []byte{0, 0}}
+	out, err = os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	var merr *FormatMismatchError
+	if err := Concat(out, a, c); !errors.As(err, &merr) || merr.Index != 1 {
+		t.Errorf("expected FormatMismatchError at index 1, got %v", err)
+	}
+}
+
+func TestSilence(t *testing.T) {
+	f := Silence(mono16, 250*time.Millisecond)
+	if len(f.Data) != 6000*2 || f.Duration() != 250*time.Millisecond {
+		t.Errorf("got %d bytes (%v)", len(f.Data), f.Duration())
+	}
+	u8 := Silence(Format{AudioFormat: FormatPCM, Channels: 2, SampleRate: 8000, BitsPerSample: 8}, 10*time.Millisecond)
+	if len(u8.Data) != 160 || u8.Data[0] != 128 {
+		t.Errorf("8bit silence = %d bytes, first %d", len(u8.Data), u8.Data[0])
+	}
+}
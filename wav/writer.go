@@ -0,0 +1,144 @@
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// headerSize は Writer が出力するヘッダー(RIFF, fmt, dataチャンクヘッダー)のバイト数です。
+const headerSize = 44
+
+// maxDataSize はRIFFのサイズフィールド(32bit)に収まるデータの最大バイト数です。
+const maxDataSize = 0xFFFFFFFF - (headerSize - 8)
+
+// Writer はPCMデータを順に書き込み、Close 時にヘッダーのサイズを確定させます。
+// データ全体をメモリに保持せずに長いWAVファイルを出力できます。
+type Writer struct {
+	ws     io.WriteSeeker
+	format Format
+	start  int64
+	n      int64
+	closed bool
+}
+
+// NewWriter は ws の現在位置から format 形式のWAVファイルを書き始めます。
+func NewWriter(ws io.WriteSeeker, format Format) (*Writer, error) {
+	if err := format.validate(); err != nil {
+		return nil, err
+	}
+	start, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("出力先の位置を取得できません: %w", err)
+	}
+	w := &Writer{ws: ws, format: format, start: start}
+	if err := w.writeHeader(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Format は書き込み中のファイルの形式を返します。
+func (w *Writer) Format() Format {
+	return w.format
+}
+
+// Len はこれまでに書き込んだPCMデータのバイト数を返します。
+func (w *Writer) Len() int64 {
+	return w.n
+}
+
+// Write はPCMデータを追記します。p はサンプルフレームの境界で区切られている必要があります。
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("クローズ済みのWriterです")
+	}
+	if len(p)%w.format.BlockAlign() != 0 {
+		return 0, fmt.Errorf("データ長 %d がブロック境界(%dバイト)に揃っていません", len(p), w.format.BlockAlign())
+	}
+	if w.n+int64(len(p)) > maxDataSize {
+		return 0, errors.New("WAVファイルの最大サイズ(4GiB)を超えます")
+	}
+	n, err := w.ws.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Close はヘッダーのサイズを書き込みます。出力先はクローズしません。
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.n%2 == 1 {
+		if _, err := w.ws.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	end, err := w.ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	_, err = w.ws.Seek(end, io.SeekStart)
+	return err
+}
+
+func (w *Writer) writeHeader() error {
+	if _, err := w.ws.Seek(w.start, io.SeekStart); err != nil {
+		return fmt.Errorf("ヘッダー位置へのシークに失敗しました: %w", err)
+	}
+	riffSize := uint32(headerSize - 8 + w.n + w.n%2)
+	b := make([]byte, 0, headerSize)
+	b = append(b, "RIFF"...)
+	b = binary.LittleEndian.AppendUint32(b, riffSize)
+	b = append(b, "WAVE"...)
+	b = append(b, "fmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 16)
+	b = append(b, formatChunk(w.format)...)
+	b = append(b, "data"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(w.n))
+	if _, err := w.ws.Write(b); err != nil {
+		return fmt.Errorf("ヘッダーの書き込みに失敗しました: %w", err)
+	}
+	return nil
+}
+
+// FormatMismatchError は結合するファイルの形式が揃っていない場合に返されます。
+type FormatMismatchError struct {
+	// Index は形式が異なるファイルの位置(0始まり)です。
+	Index int
+	Want  Format
+	Got   Format
+}
+
+func (e *FormatMismatchError) Error() string {
+	return fmt.Sprintf("%d番目のファイルの形式(%s)が先頭のファイルの形式(%s)と異なります", e.Index+1, e.Got, e.Want)
+}
+
+// Concat は files のPCMデータを順に連結して w に書き込みます。
+// 全てのファイルは先頭のファイルと同じ形式である必要があり、異なる場合は *FormatMismatchError を返します。
+func Concat(w io.WriteSeeker, files ...*File) error {
+	if len(files) == 0 {
+		return errors.New("結合するファイルがありません")
+	}
+	format := files[0].Format
+	for i, f := range files {
+		if f.Format != format {
+			return &FormatMismatchError{Index: i, Want: format, Got: f.Format}
+		}
+	}
+	ww, err := NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if _, err := ww.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	return ww.Close()
+}